@@ -0,0 +1,121 @@
+package dotenvx
+
+import (
+	"time"
+)
+
+// When true, Getenv and Environ stat the snapshot's file on every call and
+// rebuild it if the modification time moved, for long-running processes whose
+// file is replaced under them. Off by default: the point of the snapshot is to
+// touch nothing on the hot path.
+var ReloadOnChange bool
+
+// Everything Getenv and Environ need, found, read and decrypted once. A failure
+// is cached too, so a process without its key does not rescan on every call.
+type snapshot struct {
+	envFiles []EnvFile
+	vars     []EnvVar
+	last     map[string]int // each name's last assignment, the one dotenv uses
+	failed   []error        // see readVars; kept so GetenvStrict can name the cause
+	modTimes []time.Time
+	arena    *arena // with SecureMemory, where the encrypted values live
+	err      error
+}
+
+func (l *Loader) loadSnapshot() *snapshot {
+	s := &snapshot{}
+	s.envFiles, s.err = l.getEnvFiles()
+	if s.err != nil {
+		l.log().Warn("no file to decrypt", "error", s.err)
+		return s
+	}
+	s.modTimes = make([]time.Time, len(s.envFiles))
+	for i, envFile := range s.envFiles {
+		if info, err := l.stat(envFile.Path); err == nil {
+			s.modTimes[i] = info.ModTime()
+		}
+	}
+	if l.securesMemory() {
+		s.arena = &arena{}
+		l.lockKeys(s.envFiles)
+	}
+	s.vars, s.failed, s.err = l.readFiles(s.envFiles, s.arena)
+	if l.securesMemory() {
+		zeroKeys(s.envFiles)
+		l.seal(s)
+	}
+	if s.err == nil && !l.isLenient() {
+		if s.err = firstFailure(s.failed); s.err != nil {
+			s.vars = nil
+		}
+	}
+	if s.err != nil {
+		l.log().Warn("file not loaded", "file", pathOf(s.envFiles, s.err), "error", s.err)
+	} else {
+		l.log().Debug("file loaded", "file", pathOf(s.envFiles, nil), "vars", len(s.vars))
+	}
+	s.last = make(map[string]int, len(s.vars))
+	for i, v := range s.vars {
+		s.last[v.Name] = i
+	}
+	return s
+}
+
+func (l *Loader) stale(s *snapshot) bool {
+	for i, envFile := range s.envFiles {
+		if info, err := l.stat(envFile.Path); err != nil || !info.ModTime().Equal(s.modTimes[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Runs read on the snapshot, built first if there is none or it is stale, with
+// the read lock held throughout. Reload, Close and a rebuild wipe the snapshot
+// they replace under the write lock, so never while a read is still in it.
+func (l *Loader) readSnapshot(read func(*snapshot)) {
+	rebuilt := false
+	for {
+		l.mu.RLock()
+		s := l.cache
+		if s != nil && (rebuilt || !(l.revalidates() && l.stale(s))) {
+			defer l.mu.RUnlock()
+			read(s)
+			return
+		}
+		l.mu.RUnlock()
+		l.rebuild(s)
+		rebuilt = true
+	}
+}
+
+func (l *Loader) rebuild(s *snapshot) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// Another caller may have rebuilt it while this one waited for the lock
+	if l.cache == s {
+		l.cache = l.loadSnapshot()
+		if s != nil && l.securesMemory() {
+			s.wipe()
+		}
+	}
+}
+
+// Rebuilds the snapshot Getenv and Environ read from, for when the keys or the
+// file changed after the first call. Returns what finding or reading the file
+// failed with, if anything.
+func Reload() error {
+	return defaultLoader.Reload()
+}
+
+func (l *Loader) Reload() error {
+	s := l.loadSnapshot()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	old := l.cache
+	l.cache = s
+	if old != nil && l.securesMemory() {
+		old.wipe()
+	}
+	return s.err
+}
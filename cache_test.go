@@ -0,0 +1,185 @@
+package dotenvx
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Each test starts from its own directory and keys, so none may see the
+// snapshot another one left behind.
+func resetCache() {
+	defaultLoader.mu.Lock()
+	defaultLoader.cache = nil
+	defaultLoader.mu.Unlock()
+}
+
+func TestGetenv_ReadsSnapshotUntilReload(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	setKeys(t, "DOTENV_PRIVATE_KEY")
+
+	os.WriteFile(".env", []byte("TEST=before\n"), 0644)
+	if got := Getenv("TEST"); got != "before" {
+		t.Fatalf("Expected 'before', got %q", got)
+	}
+
+	os.WriteFile(".env", []byte("TEST=after\n"), 0644)
+	if got := Getenv("TEST"); got != "before" {
+		t.Errorf("Expected the snapshot's 'before' until Reload, got %q", got)
+	}
+	if err := Reload(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got := Getenv("TEST"); got != "after" {
+		t.Errorf("Expected 'after' once reloaded, got %q", got)
+	}
+}
+
+func TestGetenv_CachesFailures(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	clearEnvKeys()
+
+	if got := Getenv("TEST"); got != "" {
+		t.Fatalf("Expected \"\" with no key, got %q", got)
+	}
+	os.WriteFile(".env", []byte("TEST=value\n"), 0644)
+	t.Setenv("DOTENV_PRIVATE_KEY", testKeyHex)
+	if got := Getenv("TEST"); got != "" {
+		t.Errorf("Expected the cached failure until Reload, got %q", got)
+	}
+	if err := Reload(); err != nil || Getenv("TEST") != "value" {
+		t.Errorf("Expected 'value' once reloaded, got %q %v", Getenv("TEST"), err)
+	}
+}
+
+func TestReload_ReportsError(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	clearEnvKeys()
+
+	if err := Reload(); err == nil {
+		t.Error("Expected Reload to report no key found")
+	}
+}
+
+func TestGetenv_ReloadOnChange(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	setKeys(t, "DOTENV_PRIVATE_KEY")
+
+	ReloadOnChange = true
+	defer func() { ReloadOnChange = false }()
+
+	os.WriteFile(".env", []byte("TEST=before\n"), 0644)
+	if got := Getenv("TEST"); got != "before" {
+		t.Fatalf("Expected 'before', got %q", got)
+	}
+	os.WriteFile(".env", []byte("TEST=after\n"), 0644)
+	// Filesystems with coarse timestamps would otherwise see no change
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(".env", later, later)
+	if got := Getenv("TEST"); got != "after" {
+		t.Errorf("Expected 'after' once the mtime moved, got %q", got)
+	}
+
+	os.Remove(".env")
+	if got := Getenv("TEST"); got != "" {
+		t.Errorf("Expected \"\" once the file is gone, got %q", got)
+	}
+}
+
+func TestGetenv_ConcurrentReaders(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	setKeys(t, "DOTENV_PRIVATE_KEY")
+
+	os.WriteFile(".env", []byte("SECRET="+testCipher+"\n"), 0644)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				if got := Getenv("SECRET"); got != "hello" {
+					t.Errorf("Expected 'hello', got %q", got)
+					return
+				}
+				Environ()
+			}
+		}()
+	}
+	Reload()
+	wg.Wait()
+}
+
+func benchmarkSetup(b *testing.B) {
+	tempDir := b.TempDir()
+	originalDir, _ := os.Getwd()
+	os.Chdir(tempDir)
+	b.Cleanup(func() { os.Chdir(originalDir) })
+
+	clearEnvKeys()
+	os.Setenv("DOTENV_PRIVATE_KEY", testKeyHex)
+	b.Cleanup(func() { os.Unsetenv("DOTENV_PRIVATE_KEY") })
+	os.WriteFile(".env", []byte("PLAIN=value\nSECRET="+testCipher+"\n"), 0644)
+}
+
+// What every Getenv cost before the snapshot: discovery, a read and a decrypt
+func BenchmarkGetenv_Cold(b *testing.B) {
+	benchmarkSetup(b)
+	for b.Loop() {
+		resetCache()
+		if Getenv("SECRET") != "hello" {
+			b.Fatal("Expected 'hello'")
+		}
+	}
+}
+
+func BenchmarkGetenv_Cached(b *testing.B) {
+	benchmarkSetup(b)
+	Reload()
+	for b.Loop() {
+		if Getenv("SECRET") != "hello" {
+			b.Fatal("Expected 'hello'")
+		}
+	}
+}
+
+func BenchmarkGetenv_CachedReloadOnChange(b *testing.B) {
+	benchmarkSetup(b)
+	ReloadOnChange = true
+	defer func() { ReloadOnChange = false }()
+	Reload()
+	for b.Loop() {
+		if Getenv("SECRET") != "hello" {
+			b.Fatal("Expected 'hello'")
+		}
+	}
+}
+
+// Load, ${A} and layered files all take a name's last assignment; the
+// snapshot's lookups must agree with them.
+func TestGetenv_LastAssignmentWins(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	setKeys(t, "DOTENV_PRIVATE_KEY")
+	os.WriteFile(".env", []byte("DUP_TEST=first\nDUP_TEST=second\nDUP_REF=${DUP_TEST}\n"), 0644)
+
+	if got := Getenv("DUP_TEST"); got != "second" {
+		t.Errorf("Expected Getenv to return the last assignment, got %q", got)
+	}
+	if got, err := GetenvStrict("DUP_TEST"); got != "second" || err != nil {
+		t.Errorf("Expected GetenvStrict to return the last assignment, got %q %v", got, err)
+	}
+	if got := Getenv("DUP_REF"); got != "second" {
+		t.Errorf("Expected the reference to see the last assignment, got %q", got)
+	}
+	var cfg struct {
+		Dup string `env:"DUP_TEST"`
+	}
+	if err := Unmarshal(&cfg); err != nil || cfg.Dup != "second" {
+		t.Errorf("Expected Unmarshal to see the last assignment, got %q %v", cfg.Dup, err)
+	}
+	t.Cleanup(func() { os.Unsetenv("DUP_TEST"); os.Unsetenv("DUP_REF") })
+	if _, err := Load(); err != nil || os.Getenv("DUP_TEST") != "second" {
+		t.Errorf("Expected Load to agree, got %q %v", os.Getenv("DUP_TEST"), err)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ericpollmann/dotenvx"
+)
+
+// Repeatable and comma-separated, so -k 'DB_*' -k API_KEY and -k 'DB_*,API_KEY'
+// mean the same.
+type patternsFlag []string
+
+func (f *patternsFlag) String() string { return strings.Join(*f, ",") }
+
+func (f *patternsFlag) Set(patterns string) error {
+	*f = append(*f, strings.Split(patterns, ",")...)
+	return nil
+}
+
+func registerFilter(flags *flag.FlagSet, filter *dotenvx.KeyFilter) {
+	include, exclude := (*patternsFlag)(&filter.Include), (*patternsFlag)(&filter.Exclude)
+	flags.Var(include, "k", "only variables matching this glob; repeat for several")
+	flags.Var(include, "key", "same as -k")
+	flags.Var(exclude, "e", "skip variables matching this glob; repeat for several")
+	flags.Var(exclude, "ek", "same as -e, as dotenvx spells it")
+	flags.Var(exclude, "exclude-key", "same as -e")
+}
+
+func encryptCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("encrypt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	path := flags.String("f", ".env", "env file whose plaintext values to encrypt")
+	var filter dotenvx.KeyFilter
+	registerFilter(flags, &filter)
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) != 0 {
+		fmt.Fprintln(stderr, "usage: decrypt encrypt [-f .env.production] [-k PATTERN] [-e PATTERN]")
+		return 2
+	}
+
+	names, err := dotenvx.EncryptInPlace(*path, filter)
+	if err != nil {
+		fmt.Fprintf(stderr, "decrypt: %v\n", err)
+		return 1
+	}
+	for _, name := range names {
+		fmt.Fprintf(stdout, "%s: encrypted %s\n", *path, name)
+	}
+	return 0
+}
+
+// Printing the values is what decrypt on its own does; this one only rewrites
+// the file, so --in-place is required rather than assumed.
+func decryptCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("decrypt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	path := flags.String("f", ".env", "env file whose encrypted values to decrypt")
+	inPlace := flags.Bool("in-place", false, "write the plaintext back into the file")
+	var filter dotenvx.KeyFilter
+	registerFilter(flags, &filter)
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) != 0 || !*inPlace {
+		fmt.Fprintln(stderr, "usage: decrypt decrypt --in-place [-f .env.production] [-k PATTERN] [-e PATTERN]")
+		return 2
+	}
+
+	names, err := dotenvx.DecryptInPlace(*path, filter)
+	if err != nil {
+		fmt.Fprintf(stderr, "decrypt: %v\n", err)
+		return 1
+	}
+	for _, name := range names {
+		fmt.Fprintf(stdout, "%s: decrypted %s\n", *path, name)
+	}
+	return 0
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestEncrypt_ThenDecryptInPlace(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+
+	plain := "# keys\nDB_PASSWORD=hunter2\nDB_URL=postgres://h\nAPI_KEY='abc'\n"
+	os.WriteFile(".env.production", []byte(plain), 0644)
+
+	var stdout, stderr bytes.Buffer
+	if code := cli([]string{"encrypt", "-f", ".env.production", "-k", "DB_*,API_KEY", "-e", "*_URL"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit 0, got %d: %s", code, stderr.String())
+	}
+	if want := ".env.production: encrypted DB_PASSWORD\n.env.production: encrypted API_KEY\n"; stdout.String() != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, stdout.String())
+	}
+	encrypted, _ := os.ReadFile(".env.production")
+	if !strings.Contains(string(encrypted), "\n# keys\nDB_PASSWORD=encrypted:") || !strings.Contains(string(encrypted), "\nDB_URL=postgres://h\nAPI_KEY='encrypted:") {
+		t.Errorf("Expected a header and only the chosen values encrypted, got:\n%s", encrypted)
+	}
+
+	stdout.Reset()
+	if code := cli([]string{"decrypt", "--in-place", "-f", ".env.production", "-ek", "API_KEY"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit 0, got %d: %s", code, stderr.String())
+	}
+	if want := ".env.production: decrypted DB_PASSWORD\n"; stdout.String() != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, stdout.String())
+	}
+	decrypted, _ := os.ReadFile(".env.production")
+	if !strings.Contains(string(decrypted), "\nDB_PASSWORD=hunter2\n") || !strings.Contains(string(decrypted), "API_KEY='encrypted:") {
+		t.Errorf("Expected DB_PASSWORD back in plaintext, got:\n%s", decrypted)
+	}
+}
+
+func TestEncrypt_Errors(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+
+	for _, args := range [][]string{{"encrypt", "extra"}, {"encrypt", "-bogus"}, {"decrypt"}, {"decrypt", "--in-place", "extra"}} {
+		if code := cli(args, &bytes.Buffer{}, &bytes.Buffer{}); code != 2 {
+			t.Errorf("For %v: expected usage exit 2, got %d", args, code)
+		}
+	}
+	var stderr bytes.Buffer
+	if code := cli([]string{"encrypt", "-f", ".env.absent"}, &bytes.Buffer{}, &stderr); code != 1 || !strings.HasPrefix(stderr.String(), "decrypt: ") {
+		t.Errorf("Expected exit 1 for a missing file, got %d: %s", code, stderr.String())
+	}
+	os.WriteFile(".env", []byte(testHeader+"A=1\n"), 0644)
+	if code := cli([]string{"encrypt", "-k", "["}, &bytes.Buffer{}, &bytes.Buffer{}); code != 1 {
+		t.Errorf("Expected exit 1 for a malformed pattern, got %d", code)
+	}
+	if code := cli([]string{"decrypt", "--in-place"}, &bytes.Buffer{}, &bytes.Buffer{}); code != 1 {
+		t.Errorf("Expected exit 1 without a key, got %d", code)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"strings"
+
+	"github.com/ericpollmann/dotenvx"
+)
+
+type filesFlag []string
+
+func (f *filesFlag) String() string { return strings.Join(*f, ",") }
+
+func (f *filesFlag) Set(path string) error {
+	*f = append(*f, path)
+	return nil
+}
+
+// Which files to decrypt, as dotenvx run takes them. run and get register a
+// fileOptions of their own and merge it into the top-level one, so decrypt -f a
+// run -- cmd and decrypt run -f a -- cmd mean the same.
+type fileOptions struct {
+	files       filesFlag
+	overload    bool
+	unsetKeys   bool
+	convention  string
+	environment string
+}
+
+func (o *fileOptions) register(flags *flag.FlagSet) {
+	flags.Var(&o.files, "f", "env file to load; repeat to layer several, the first to set a variable winning")
+	flags.BoolVar(&o.overload, "overload", false, "let later files, and the files over the process environment, win")
+	flags.BoolVar(&o.unsetKeys, "unset-keys", false, "remove DOTENV_PRIVATE_KEY* from the environment once read, so a command run does not inherit them")
+	flags.StringVar(&o.convention, "convention", "", "load a framework's files after any -f: nextjs")
+	flags.StringVar(&o.environment, "env", "", "environment for --convention (default $NODE_ENV, else development)")
+}
+
+// Registering the top-level fields again would reset them to their defaults,
+// so a subcommand's flags are parsed apart and added here: its files after the
+// top-level ones, and its switches and names over theirs.
+func (o *fileOptions) merge(sub *fileOptions) {
+	o.files = append(o.files, sub.files...)
+	o.overload = o.overload || sub.overload
+	o.unsetKeys = o.unsetKeys || sub.unsetKeys
+	if sub.convention != "" {
+		o.convention = sub.convention
+	}
+	if sub.environment != "" {
+		o.environment = sub.environment
+	}
+}
+
+func (o *fileOptions) loader() (*dotenvx.Loader, error) {
+	opts := []dotenvx.Option{dotenvx.WithLogger(dotenvx.Logger)}
+	files := o.files
+	if o.convention != "" {
+		environment := o.environment
+		if environment == "" {
+			environment = os.Getenv("NODE_ENV")
+		}
+		if environment == "" {
+			environment = "development"
+		}
+		convention, err := dotenvx.ConventionFiles(o.convention, environment)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, convention...)
+	}
+	if len(files) > 0 {
+		opts = append(opts, dotenvx.WithFiles(files...))
+	}
+	if o.overload {
+		opts = append(opts, dotenvx.WithOverload())
+	}
+	if o.unsetKeys {
+		opts = append(opts, dotenvx.WithUnsetKeys())
+	}
+	return dotenvx.New(opts...), nil
+}
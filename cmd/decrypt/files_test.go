@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCLI_LayersFilesFirstWins(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	os.WriteFile(".env.local", []byte("SHARED=local\n"), 0644)
+	os.WriteFile(".env", []byte("SHARED="+testCipher+"\nBASE=base\n"), 0644)
+	t.Setenv("DOTENV_PRIVATE_KEY", testKeyHex)
+
+	var stdout bytes.Buffer
+	if code := cli([]string{"-f", ".env.local", "-f", ".env"}, &stdout, &bytes.Buffer{}); code != 0 {
+		t.Fatalf("Expected exit 0, got %d", code)
+	}
+	if stdout.String() != "SHARED=local\nBASE=base\n" {
+		t.Errorf("Expected .env.local to win, got %q", stdout.String())
+	}
+
+	stdout.Reset()
+	cli([]string{"-f", ".env.local", "-f", ".env", "--overload"}, &stdout, &bytes.Buffer{})
+	if stdout.String() != "SHARED=hello\nBASE=base\n" {
+		t.Errorf("Expected .env to win with --overload, got %q", stdout.String())
+	}
+}
+
+func TestCLI_OnePlainFileNeedsNoKey(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	os.WriteFile(".env.local", []byte("PLAIN=value\n"), 0644)
+	os.Unsetenv("DOTENV_PRIVATE_KEY")
+
+	var stdout, stderr bytes.Buffer
+	if code := cli([]string{"get", "-f", ".env.local", "PLAIN"}, &stdout, &stderr); code != 0 || stdout.String() != "value\n" {
+		t.Errorf("Expected one -f to load like several, got %d %q %s", code, stdout.String(), stderr.String())
+	}
+}
+
+func TestCLI_ConventionNextjs(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	os.WriteFile(".env.production.local", []byte("A=production.local\n"), 0644)
+	os.WriteFile(".env.local", []byte("A=local\nB=local\n"), 0644)
+	os.WriteFile(".env.production", []byte("B=production\nC=production\n"), 0644)
+	os.WriteFile(".env", []byte("C=env\nD=env\n"), 0644)
+	os.WriteFile(".env.test", []byte("T=test\n"), 0644)
+
+	var stdout bytes.Buffer
+	cli([]string{"--convention", "nextjs", "--env", "production"}, &stdout, &bytes.Buffer{})
+	if want := "A=production.local\nB=local\nC=production\nD=env\n"; stdout.String() != want {
+		t.Errorf("Expected %q, got %q", want, stdout.String())
+	}
+
+	stdout.Reset()
+	t.Setenv("NODE_ENV", "test")
+	cli([]string{"--convention", "nextjs"}, &stdout, &bytes.Buffer{})
+	if want := "T=test\nC=env\nD=env\n"; stdout.String() != want {
+		t.Errorf("Expected NODE_ENV=test to skip .env.local, got %q", stdout.String())
+	}
+
+	var stderr bytes.Buffer
+	if code := cli([]string{"--convention", "rails"}, &stdout, &stderr); code != 2 || !strings.Contains(stderr.String(), "rails") {
+		t.Errorf("Expected exit 2 naming rails, got %d %q", code, stderr.String())
+	}
+}
+
+func TestRun_FilesAndOverload(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	calls := stubExec(t)
+	os.WriteFile(".env.local", []byte("FROM=local\n"), 0644)
+	os.WriteFile(".env", []byte("FROM=env\nGREETING="+testCipher+"\n"), 0644)
+	t.Setenv("DOTENV_PRIVATE_KEY", testKeyHex)
+	t.Setenv("GREETING", "process")
+
+	cli([]string{"run", "-f", ".env.local", "-f", ".env", "--", "true"}, os.Stdout, &bytes.Buffer{})
+	cli([]string{"-f", ".env.local", "-f", ".env", "run", "--overload", "--", "true"}, os.Stdout, &bytes.Buffer{})
+	cli([]string{"-f", ".env.local", "-f", ".env", "--overload", "run", "--", "true"}, os.Stdout, &bytes.Buffer{})
+	if len(*calls) != 3 {
+		t.Fatalf("Expected three execs, got %d", len(*calls))
+	}
+	if env := (*calls)[0].env; !slices.Contains(env, "FROM=local") || !slices.Contains(env, "GREETING=process") {
+		t.Errorf("Expected .env.local and the process to win, got %v", env)
+	}
+	for _, call := range (*calls)[1:] {
+		if !slices.Contains(call.env, "FROM=env") || !slices.Contains(call.env, "GREETING=hello") {
+			t.Errorf("Expected .env over .env.local and the process with --overload before or after run, got %v", call.env)
+		}
+	}
+	var stdout bytes.Buffer
+	if code := cli([]string{"-f", ".env.local", "-f", ".env", "--overload", "get", "FROM"}, &stdout, &bytes.Buffer{}); code != 0 || stdout.String() != "env\n" {
+		t.Errorf("Expected a top-level --overload to reach get, got %d %q", code, stdout.String())
+	}
+}
+
+func TestRun_ConventionBeforeRun(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	calls := stubExec(t)
+	os.WriteFile(".env.local", []byte("A=local\n"), 0644)
+	os.WriteFile(".env", []byte("B=env\n"), 0644)
+
+	cli([]string{"--convention", "nextjs", "--env", "development", "run", "-f", ".env", "--", "true"}, os.Stdout, &bytes.Buffer{})
+	if len(*calls) != 1 || !slices.Contains((*calls)[0].env, "A=local") || !slices.Contains((*calls)[0].env, "B=env") {
+		t.Errorf("Expected the convention's .env.local alongside run's -f, got %v", *calls)
+	}
+}
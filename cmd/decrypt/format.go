@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/ericpollmann/dotenvx"
+)
+
+// Each writes vars in file order for one consumer, escaped so that consumer
+// reads back exactly the decrypted value, or fails on a value it cannot hold.
+var formats = map[string]func(io.Writer, []dotenvx.EnvVar) error{
+	"raw":     writeRaw,
+	"shell":   writeShell,
+	"json":    writeJSON,
+	"yaml":    writeYAML,
+	"dotenv":  writeDotenv,
+	"docker":  writeDocker,
+	"systemd": writeSystemd,
+}
+
+func formatNames() string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return strings.Join(names, ", ")
+}
+
+// Environ's NAME=value lines back into variables; a name never holds "=".
+func envVars(environ []string) []dotenvx.EnvVar {
+	vars := make([]dotenvx.EnvVar, 0, len(environ))
+	for _, env := range environ {
+		name, value, _ := strings.Cut(env, "=")
+		vars = append(vars, dotenvx.EnvVar{Name: name, Value: value})
+	}
+	return vars
+}
+
+// What decrypt has always printed: unescaped, for eyes rather than parsers.
+func writeRaw(w io.Writer, vars []dotenvx.EnvVar) error {
+	for _, v := range vars {
+		fmt.Fprintf(w, "%s=%s\n", v.Name, v.Value)
+	}
+	return nil
+}
+
+var shellName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// For eval: single quotes keep everything literal, $ and newlines included,
+// and a single quote is closed, escaped and reopened.
+func writeShell(w io.Writer, vars []dotenvx.EnvVar) error {
+	for _, v := range vars {
+		if !shellName.MatchString(v.Name) {
+			return fmt.Errorf("%s is not a shell variable name", v.Name)
+		}
+	}
+	for _, v := range vars {
+		fmt.Fprintf(w, "export %s='%s'\n", v.Name, strings.ReplaceAll(v.Value, "'", `'\''`))
+	}
+	return nil
+}
+
+// An object rather than an array, keeping the file's order, which a map would
+// lose.
+func writeJSON(w io.Writer, vars []dotenvx.EnvVar) error {
+	if len(vars) == 0 {
+		_, err := io.WriteString(w, "{}\n")
+		return err
+	}
+	var b strings.Builder
+	b.WriteString("{\n")
+	for i, v := range vars {
+		fmt.Fprintf(&b, "  %s: %s", jsonString(v.Name), jsonString(v.Value))
+		if i < len(vars)-1 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('\n')
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Without the < escapes json.Marshal adds for HTML, which no reader here
+// needs.
+func jsonString(s string) string {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// Names a YAML 1.1 parser would read as a boolean or null, so they are quoted.
+var yamlWords = map[string]bool{"y": true, "yes": true, "n": true, "no": true, "on": true, "off": true, "true": true, "false": true, "null": true}
+
+// A JSON string is a valid YAML double-quoted scalar, escapes and all, so the
+// values need no quoting rules of their own.
+func writeYAML(w io.Writer, vars []dotenvx.EnvVar) error {
+	if len(vars) == 0 {
+		_, err := io.WriteString(w, "{}\n")
+		return err
+	}
+	for _, v := range vars {
+		name := v.Name
+		if !shellName.MatchString(name) || yamlWords[strings.ToLower(name)] {
+			name = jsonString(name)
+		}
+		fmt.Fprintf(w, "%s: %s\n", name, jsonString(v.Value))
+	}
+	return nil
+}
+
+// Double quotes, which dotenv unescapes, with $ escaped too so dotenvx's
+// expansion leaves the value as it is.
+func writeDotenv(w io.Writer, vars []dotenvx.EnvVar) error {
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "$", `\$`)
+	for _, v := range vars {
+		fmt.Fprintf(w, "%s=\"%s\"\n", v.Name, escape.Replace(v.Value))
+	}
+	return nil
+}
+
+// docker run --env-file takes each line after the first = verbatim, quotes and
+// all, with no escape for a newline.
+func writeDocker(w io.Writer, vars []dotenvx.EnvVar) error {
+	for _, v := range vars {
+		if strings.ContainsAny(v.Value, "\n\r") {
+			return fmt.Errorf("%s: docker env files cannot hold a newline", v.Name)
+		}
+	}
+	return writeRaw(w, vars)
+}
+
+// systemd's EnvironmentFile= unescapes \" \\ \` and \$ inside double quotes and
+// keeps a newline there as part of the value.
+func writeSystemd(w io.Writer, vars []dotenvx.EnvVar) error {
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "`", "\\`", "$", `\$`)
+	for _, v := range vars {
+		fmt.Fprintf(w, "%s=\"%s\"\n", v.Name, escape.Replace(v.Value))
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ericpollmann/dotenvx"
+)
+
+var awkwardVars = []dotenvx.EnvVar{
+	{Name: "NEWLINE", Value: "first\nsecond\r\n"},
+	{Name: "QUOTES", Value: `it's "quoted" and ` + "`ticked`"},
+	{Name: "DOLLAR", Value: "$HOME ${USER} \\$"},
+	{Name: "UNICODE", Value: "héllo ☃ <&> \u2028"},
+	{Name: "EMPTY", Value: ""},
+	{Name: "yes", Value: "no"},
+}
+
+func formatted(t *testing.T, format string, vars []dotenvx.EnvVar) string {
+	t.Helper()
+	var out bytes.Buffer
+	if err := formats[format](&out, vars); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func TestFormat_Shell(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no sh")
+	}
+	script := filepath.Join(t.TempDir(), "vars.sh")
+	os.WriteFile(script, []byte(formatted(t, "shell", awkwardVars)), 0644)
+	for _, v := range awkwardVars {
+		out, err := exec.Command(sh, "-c", `. "$0" && printf %s "$`+v.Name+`"`, script).Output()
+		if err != nil || string(out) != v.Value {
+			t.Errorf("Expected sh to read %s=%q back, got %q %v", v.Name, v.Value, out, err)
+		}
+	}
+	if err := writeShell(&bytes.Buffer{}, []dotenvx.EnvVar{{Name: "NOT.SHELL", Value: "x"}}); err == nil {
+		t.Error("Expected a name sh cannot export rejected")
+	}
+}
+
+func TestFormat_JSON(t *testing.T) {
+	out := formatted(t, "json", awkwardVars)
+	var got map[string]string
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range awkwardVars {
+		if got[v.Name] != v.Value {
+			t.Errorf("Expected %s=%q, got %q", v.Name, v.Value, got[v.Name])
+		}
+	}
+	if !strings.HasPrefix(out, "{\n  \"NEWLINE\": \"first\\nsecond\\r\\n\",\n  \"QUOTES\"") || !strings.Contains(out, `"héllo ☃ <&> \u2028"`) {
+		t.Errorf("Expected file order and readable unicode, got:\n%s", out)
+	}
+	if out := formatted(t, "json", nil); out != "{}\n" {
+		t.Errorf("Expected an empty object, got %q", out)
+	}
+}
+
+func TestFormat_YAML(t *testing.T) {
+	want := `NEWLINE: "first\nsecond\r\n"
+QUOTES: "it's \"quoted\" and ` + "`ticked`" + `"
+DOLLAR: "$HOME ${USER} \\$"
+UNICODE: "héllo ☃ <&> \u2028"
+EMPTY: ""
+"yes": "no"
+`
+	if out := formatted(t, "yaml", awkwardVars); out != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, out)
+	}
+	if out := formatted(t, "yaml", []dotenvx.EnvVar{{Name: "A.B-C", Value: "x"}}); out != "\"A.B-C\": \"x\"\n" {
+		t.Errorf("Expected a dotted name quoted, got %q", out)
+	}
+}
+
+func TestFormat_Dotenv(t *testing.T) {
+	out := formatted(t, "dotenv", awkwardVars)
+	got, err := dotenvx.Decrypt(strings.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range awkwardVars {
+		if got[i].Name != v.Name || got[i].Value != v.Value {
+			t.Errorf("Expected %s=%q read back, got %s=%q", v.Name, v.Value, got[i].Name, got[i].Value)
+		}
+	}
+	if strings.Count(out, "\n") != len(awkwardVars) {
+		t.Errorf("Expected one line per variable, got:\n%s", out)
+	}
+}
+
+func TestFormat_Docker(t *testing.T) {
+	vars := awkwardVars[1:]
+	want := "QUOTES=it's \"quoted\" and `ticked`\nDOLLAR=$HOME ${USER} \\$\nUNICODE=héllo ☃ <&> \u2028\nEMPTY=\nyes=no\n"
+	if out := formatted(t, "docker", vars); out != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, out)
+	}
+	var out bytes.Buffer
+	if err := writeDocker(&out, awkwardVars); err == nil || !strings.Contains(err.Error(), "NEWLINE") || out.Len() != 0 {
+		t.Errorf("Expected NEWLINE rejected before anything is written, got %v %q", err, out.String())
+	}
+}
+
+func TestFormat_Systemd(t *testing.T) {
+	want := "NEWLINE=\"first\nsecond\r\n\"\n" +
+		"QUOTES=\"it's \\\"quoted\\\" and \\`ticked\\`\"\n" +
+		"DOLLAR=\"\\$HOME \\${USER} \\\\\\$\"\n" +
+		"UNICODE=\"héllo ☃ <&> \u2028\"\n" +
+		"EMPTY=\"\"\n" +
+		"yes=\"no\"\n"
+	if out := formatted(t, "systemd", awkwardVars); out != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, out)
+	}
+}
+
+func TestCLI_Format(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	os.WriteFile(".env", []byte("A=\"two\\nlines\"\nB='$x'\n"), 0644)
+	t.Setenv("DOTENV_PRIVATE_KEY", testKeyHex)
+
+	var stdout bytes.Buffer
+	if code := cli([]string{"--format", "json"}, &stdout, &bytes.Buffer{}); code != 0 {
+		t.Fatalf("Expected exit 0, got %d", code)
+	}
+	if want := "{\n  \"A\": \"two\\nlines\",\n  \"B\": \"$x\"\n}\n"; stdout.String() != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, stdout.String())
+	}
+	stdout.Reset()
+	if code := cli(nil, &stdout, &bytes.Buffer{}); code != 0 || stdout.String() != "A=two\nlines\nB=$x\n" {
+		t.Errorf("Expected raw output by default, got %d %q", code, stdout.String())
+	}
+
+	var stderr bytes.Buffer
+	if code := cli([]string{"--format", "docker"}, &bytes.Buffer{}, &stderr); code != 1 || !strings.Contains(stderr.String(), "A: docker env files cannot hold a newline") {
+		t.Errorf("Expected exit 1 for a newline in a docker env file, got %d: %s", code, stderr.String())
+	}
+	stderr.Reset()
+	t.Setenv("DOTENV_PRIVATE_KEY", strings.Repeat("1", 64))
+	os.WriteFile(".env", []byte(testHeader+testGreeting), 0644)
+	stdout.Reset()
+	if code := cli([]string{"--format", "json"}, &stdout, &stderr); code != 2 || stdout.Len() != 0 || !strings.Contains(stderr.String(), ".env") {
+		t.Errorf("Expected exit 2 and nothing printed with the wrong key, got %d %q %s", code, stdout.String(), stderr.String())
+	}
+	stderr.Reset()
+	if code := cli([]string{"--format", "toml"}, &bytes.Buffer{}, &stderr); code != 2 || !strings.Contains(stderr.String(), "dotenv, json") {
+		t.Errorf("Expected usage exit 2 listing the formats, got %d: %s", code, stderr.String())
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/ericpollmann/dotenvx"
+)
+
+// For scripts: DB_PASSWORD=$(decrypt get DB_PASSWORD). Exits 1 when a name is
+// not in the files and 2 when they could not be decrypted, printing nothing
+// either way so a caller never reads one value in another's place.
+func getCommand(args []string, files *fileOptions, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("get", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("format", "", "print names with the values as "+formatNames()+" (default values alone, one per line)")
+	all := flags.Bool("all", false, "every variable rather than those named, as raw unless --format")
+	var own fileOptions
+	own.register(flags)
+	names, err := parseArgs(flags, args)
+	if err != nil {
+		return 2
+	}
+	files.merge(&own)
+	if *all == (len(names) > 0) {
+		fmt.Fprintln(stderr, "usage: decrypt get NAME... | --all [--format json] [-f file]... [--overload] [--convention nextjs]")
+		return 2
+	}
+	if *all && *format == "" {
+		*format = "raw"
+	}
+	write, ok := formats[*format]
+	if !ok && *format != "" {
+		fmt.Fprintf(stderr, "decrypt: --format: %q is not one of %s\n", *format, formatNames())
+		return 2
+	}
+	loader, err := files.loader()
+	if err != nil {
+		fmt.Fprintf(stderr, "decrypt: %v\n", err)
+		return 2
+	}
+
+	var vars []dotenvx.EnvVar
+	if *all {
+		env, err := loader.EnvironStrict()
+		if err != nil {
+			fmt.Fprintf(stderr, "decrypt: %v\n", err)
+			return 2
+		}
+		vars = envVars(env)
+	}
+	code := 0
+	for _, name := range names {
+		value, err := loader.GetenvStrict(name)
+		if err != nil {
+			fmt.Fprintf(stderr, "decrypt: %v\n", err)
+			if !errors.Is(err, dotenvx.ErrNotFound) {
+				return 2
+			}
+			code = 1
+			continue
+		}
+		vars = append(vars, dotenvx.EnvVar{Name: name, Value: value})
+	}
+	if code != 0 {
+		return code
+	}
+
+	if write == nil {
+		for _, v := range vars {
+			fmt.Fprintln(stdout, v.Value)
+		}
+		return 0
+	}
+	if err := write(stdout, vars); err != nil {
+		fmt.Fprintf(stderr, "decrypt: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+const testGreeting = `GREETING="encrypted:BL8cvfR8496FAJV3dbdSZj/D6qlhOc3lAhuAB24AGp4WASPH8BBoe21T+T9jlO/M0GY03RZ94Etk7VPWIP21vh+YLGu0fWe2usFdTFs+/BnlsT8K8+V9Xte/yXA2NhrRxy3T7ygL"` + "\n"
+
+func TestGet_Values(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	os.WriteFile(".env", []byte(testHeader+testGreeting+"PLAIN=two words\n"), 0644)
+	t.Setenv("DOTENV_PRIVATE_KEY", testKeyHex)
+
+	var stdout, stderr bytes.Buffer
+	if code := cli([]string{"get", "GREETING"}, &stdout, &stderr); code != 0 || stdout.String() != "hello\n" {
+		t.Errorf("Expected hello, got %d %q %s", code, stdout.String(), stderr.String())
+	}
+	stdout.Reset()
+	if code := cli([]string{"get", "PLAIN", "GREETING"}, &stdout, &stderr); code != 0 || stdout.String() != "two words\nhello\n" {
+		t.Errorf("Expected both values in the order asked, got %d %q", code, stdout.String())
+	}
+	stdout.Reset()
+	if code := cli([]string{"get", "GREETING", "PLAIN", "--format", "json"}, &stdout, &stderr); code != 0 || stdout.String() != "{\n  \"GREETING\": \"hello\",\n  \"PLAIN\": \"two words\"\n}\n" {
+		t.Errorf("Expected a JSON object, got %d %q", code, stdout.String())
+	}
+
+	stdout.Reset()
+	if code := cli([]string{"get", "--all"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit 0, got %d: %s", code, stderr.String())
+	}
+	var bare bytes.Buffer
+	cli(nil, &bare, &stderr)
+	if stdout.String() != bare.String() || !strings.Contains(stdout.String(), "GREETING=hello\n") {
+		t.Errorf("Expected --all to print what decrypt alone does, got %q and %q", stdout.String(), bare.String())
+	}
+}
+
+func TestGet_Missing(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	os.WriteFile(".env", []byte(testHeader+testGreeting), 0644)
+	t.Setenv("DOTENV_PRIVATE_KEY", testKeyHex)
+
+	var stdout, stderr bytes.Buffer
+	if code := cli([]string{"get", "GREETING", "ABSENT"}, &stdout, &stderr); code != 1 || stdout.Len() != 0 || !strings.Contains(stderr.String(), "ABSENT") {
+		t.Errorf("Expected exit 1 naming ABSENT and nothing printed, got %d %q %s", code, stdout.String(), stderr.String())
+	}
+}
+
+func TestGet_DecryptFailure(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	os.WriteFile(".env.ci", []byte(testHeader+"TOKEN=encrypted:AAAA\nPLAIN=x\n"), 0644)
+	t.Setenv("DOTENV_PRIVATE_KEY_CI", testKeyHex)
+
+	for _, args := range [][]string{{"get", "-f", ".env.ci", "ABSENT", "PLAIN"}, {"-f", ".env.ci", "get", "--all"}} {
+		var stdout, stderr bytes.Buffer
+		if code := cli(args, &stdout, &stderr); code != 2 || stdout.Len() != 0 || !strings.Contains(stderr.String(), "TOKEN") {
+			t.Errorf("For %v: expected exit 2 naming TOKEN, got %d %q %s", args, code, stdout.String(), stderr.String())
+		}
+	}
+	os.Unsetenv("DOTENV_PRIVATE_KEY_CI")
+	if code := cli([]string{"get", "-f", ".env.ci", "PLAIN"}, &bytes.Buffer{}, &bytes.Buffer{}); code != 2 {
+		t.Errorf("Expected exit 2 without a key, got %d", code)
+	}
+}
+
+func TestGet_Usage(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+
+	for _, args := range [][]string{{"get"}, {"get", "A", "--all"}, {"get", "A", "--format", "toml"}, {"get", "-bogus"}} {
+		if code := cli(args, &bytes.Buffer{}, &bytes.Buffer{}); code != 2 {
+			t.Errorf("For %v: expected usage exit 2, got %d", args, code)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/ericpollmann/dotenvx"
+)
+
+func keypairCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("keypair", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	env := flags.String("env", "", "environment name: staging writes .env.staging and DOTENV_PRIVATE_KEY_STAGING")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) != 0 {
+		fmt.Fprintln(stderr, "usage: decrypt keypair [--env staging]")
+		return 2
+	}
+
+	path := ".env"
+	if *env != "" {
+		path += "." + *env
+	}
+	publicKeyHex, err := dotenvx.GenerateKeyPair(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "decrypt: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "%s: %s\n", path, publicKeyHex)
+	return 0
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestKeypair_WritesHeaderAndKeys(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+
+	var stdout bytes.Buffer
+	if code := cli([]string{"keypair", "--env", "staging"}, &stdout, &bytes.Buffer{}); code != 0 {
+		t.Fatalf("Expected exit 0, got %d", code)
+	}
+	if !strings.HasPrefix(stdout.String(), ".env.staging: 0") {
+		t.Errorf("Expected the compressed public key printed, got %q", stdout.String())
+	}
+	content, _ := os.ReadFile(".env.staging")
+	if !strings.Contains(string(content), "DOTENV_PUBLIC_KEY_STAGING=") {
+		t.Errorf("Expected a DOTENV_PUBLIC_KEY_STAGING header, got:\n%s", content)
+	}
+	keys, _ := os.ReadFile(".env.keys")
+	if !strings.Contains(string(keys), "DOTENV_PRIVATE_KEY_STAGING=") {
+		t.Errorf("Expected DOTENV_PRIVATE_KEY_STAGING in .env.keys, got:\n%s", keys)
+	}
+}
+
+func TestKeypair_DefaultsToDotEnv(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+
+	if code := cli([]string{"keypair"}, &bytes.Buffer{}, &bytes.Buffer{}); code != 0 {
+		t.Fatalf("Expected exit 0, got %d", code)
+	}
+	if _, err := os.Stat(".env"); err != nil {
+		t.Errorf("Expected .env written: %v", err)
+	}
+	if code := cli([]string{"keypair"}, &bytes.Buffer{}, &bytes.Buffer{}); code != 1 {
+		t.Errorf("Expected exit 1 for a second keypair on .env, got %d", code)
+	}
+}
+
+func TestKeypair_Usage(t *testing.T) {
+	for _, args := range [][]string{{"keypair", "staging"}, {"keypair", "-bogus"}} {
+		if code := cli(args, &bytes.Buffer{}, &bytes.Buffer{}); code != 2 {
+			t.Errorf("For %v: expected usage exit 2, got %d", args, code)
+		}
+	}
+}
@@ -1,13 +1,104 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
+	"log/slog"
+	"os"
 
 	"github.com/ericpollmann/dotenvx"
 )
 
 func main() {
-	for _, env := range dotenvx.Environ() {
-		fmt.Println(env)
+	if code := cli(os.Args[1:], os.Stdout, os.Stderr); code != 0 {
+		os.Exit(code)
+	}
+}
+
+func cli(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("decrypt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	logLevel := flags.String("log-level", "", "log key discovery and decryption to stderr: debug, info, warn or error")
+	format := flags.String("format", "raw", "how to print the variables: "+formatNames())
+	var files fileOptions
+	files.register(flags)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *logLevel != "" {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(*logLevel)); err != nil {
+			fmt.Fprintf(stderr, "decrypt: --log-level: %v\n", err)
+			return 2
+		}
+		dotenvx.Logger = slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: level}))
+	}
+
+	args = flags.Args()
+	if len(args) > 0 {
+		switch args[0] {
+		case "run":
+			return runCommand(args[1:], &files, stderr)
+		case "get":
+			return getCommand(args[1:], &files, stdout, stderr)
+		case "set":
+			return setCommand(args[1:], stderr)
+		case "keypair":
+			return keypairCommand(args[1:], stdout, stderr)
+		case "rotate":
+			return rotateCommand(args[1:], stdout, stderr)
+		case "encrypt":
+			return encryptCommand(args[1:], stdout, stderr)
+		case "decrypt":
+			return decryptCommand(args[1:], stdout, stderr)
+		}
+		// Printing everything is the one thing a typo must not fall back to
+		fmt.Fprintf(stderr, "decrypt: unknown command %q; want run, get, set, keypair, rotate, encrypt or decrypt\n", args[0])
+		return 2
+	}
+	write, ok := formats[*format]
+	if !ok {
+		fmt.Fprintf(stderr, "decrypt: --format: %q is not one of %s\n", *format, formatNames())
+		return 2
+	}
+	loader, err := files.loader()
+	if err != nil {
+		fmt.Fprintf(stderr, "decrypt: %v\n", err)
+		return 2
+	}
+	// raw is for eyes and keeps printing what it can; anything else feeds a
+	// program, which an incomplete or empty environment would only mislead.
+	env := loader.Environ()
+	if *format != "raw" {
+		if env, err = loader.EnvironStrict(); err != nil {
+			fmt.Fprintf(stderr, "decrypt: %v\n", err)
+			return 2
+		}
+	}
+	if err := write(stdout, envVars(env)); err != nil {
+		fmt.Fprintf(stderr, "decrypt: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// flag stops at the first positional argument, but dotenvx's own commands take
+// their flags after it (set NAME VALUE -f .env.production), so keep going until
+// the arguments or a "--" run out.
+func parseArgs(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := flags.Args()
+		if consumed := args[:len(args)-len(rest)]; len(consumed) > 0 && consumed[len(consumed)-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		positional, args = append(positional, rest[0]), rest[1:]
 	}
 }
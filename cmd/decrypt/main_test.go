@@ -3,9 +3,12 @@ package main
 import (
 	"bytes"
 	"io"
+	"log/slog"
 	"os"
 	"strings"
 	"testing"
+
+	"github.com/ericpollmann/dotenvx"
 )
 
 func inTempDir(t *testing.T) string {
@@ -28,6 +31,11 @@ func restoreStdout(newStdout, oldStdout *os.File) {
 }
 
 func captureStdout(fn func()) string {
+	// main reads os.Args, which under go test holds the test binary's flags
+	oldArgs := os.Args
+	os.Args = []string{"decrypt"}
+	defer func() { os.Args = oldArgs }()
+
 	newStdin, newStdout, oldStdout := capturedStdout()
 	fn()
 	restoreStdout(newStdout, oldStdout)
@@ -53,6 +61,7 @@ QUOTED_PLAIN="quoted value"
 	os.Setenv("DOTENV_PRIVATE_KEY", "2ff9d3716a37e630e0643447beac508a1e9963444d3ca00a6a22dbf2970dc03d")
 	defer os.Unsetenv("DOTENV_PRIVATE_KEY")
 
+	dotenvx.Reload()
 	output := captureStdout(func() { main() })
 
 	// Check that output contains expected patterns
@@ -75,9 +84,51 @@ func TestMainNoEnv(t *testing.T) {
 
 	os.Unsetenv("DOTENV_PRIVATE_KEY")
 	os.Unsetenv("DOTENV_PRIVATE_KEY_PRODUCTION")
+	dotenvx.Reload()
 	output := captureStdout(func() { main() })
 
 	if strings.TrimSpace(output) != "" {
 		t.Errorf("Expected empty output, got: %s", output)
 	}
 }
+
+func TestCLI_LogLevelWritesToStderr(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	// Restored rather than replaced: Debug only logs while Logger is the default
+	defer func(logger *slog.Logger) { dotenvx.Logger = logger }(dotenvx.Logger)
+	stubExec(t)
+
+	os.Setenv("DOTENV_PRIVATE_KEY", testKeyHex)
+	defer os.Unsetenv("DOTENV_PRIVATE_KEY")
+	os.WriteFile(".env", []byte("SECRET="+testCipher+"\n"), 0644)
+
+	var stdout, stderr bytes.Buffer
+	cli([]string{"--log-level", "debug", "run", "--", "true"}, &stdout, &stderr)
+	if !strings.Contains(stderr.String(), "level=DEBUG msg=decrypted") || !strings.Contains(stderr.String(), "var=SECRET") {
+		t.Errorf("Expected a debug event for SECRET on stderr, got %q", stderr.String())
+	}
+	if strings.Contains(stderr.String(), "hello") || stdout.Len() != 0 {
+		t.Errorf("Expected no value in the log and nothing on stdout, got %q and %q", stderr.String(), stdout.String())
+	}
+}
+
+func TestCLI_BadLogLevel(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := cli([]string{"--log-level", "loud"}, &stdout, &stderr); code != 2 {
+		t.Errorf("Expected exit 2, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "--log-level") {
+		t.Errorf("Expected the flag named on stderr, got %q", stderr.String())
+	}
+}
+
+func TestCLI_UnknownCommandPrintsNothing(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	os.WriteFile(".env", []byte("SECRET="+testCipher+"\n"), 0644)
+	t.Setenv("DOTENV_PRIVATE_KEY", testKeyHex)
+
+	var stdout, stderr bytes.Buffer
+	if code := cli([]string{"rotat", "--dry-run"}, &stdout, &stderr); code != 2 || stdout.Len() != 0 || !strings.Contains(stderr.String(), `"rotat"`) {
+		t.Errorf("Expected usage exit 2 naming rotat and nothing printed, got %d %q %s", code, stdout.String(), stderr.String())
+	}
+}
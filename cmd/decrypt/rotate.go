@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/ericpollmann/dotenvx"
+)
+
+func rotateCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("rotate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	path := flags.String("f", ".env", "env file to re-encrypt under a new keypair")
+	dryRun := flags.Bool("dry-run", false, "list what would change without writing anything")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) != 0 {
+		fmt.Fprintln(stderr, "usage: decrypt rotate [-f .env.production] [--dry-run]")
+		return 2
+	}
+
+	rotation, err := dotenvx.PlanRotation(*path)
+	if err != nil {
+		fmt.Fprintf(stderr, "decrypt: %v\n", err)
+		return 1
+	}
+	if *dryRun {
+		for _, name := range rotation.Names {
+			fmt.Fprintf(stdout, "%s: would re-encrypt %s\n", rotation.Path, name)
+		}
+		fmt.Fprintf(stdout, "%s: would replace %s\n", rotation.Path, rotation.PublicKeyVar)
+		fmt.Fprintf(stdout, "%s: would replace %s\n", rotation.KeysPath, rotation.PrivateKeyVar)
+		return 0
+	}
+	if err := rotation.Apply(); err != nil {
+		fmt.Fprintf(stderr, "decrypt: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "%s: %s\n", rotation.Path, rotation.PublicKey)
+	if rotation.KeyFromEnvironment {
+		fmt.Fprintf(stderr, "decrypt: %s is set in the environment; replace it with the new key from %s\n", rotation.PrivateKeyVar, rotation.KeysPath)
+	}
+	return 0
+}
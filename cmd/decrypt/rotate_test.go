@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRotate_DryRunThenRotate(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+
+	cli([]string{"keypair", "--env", "production"}, &bytes.Buffer{}, &bytes.Buffer{})
+	cli([]string{"set", "GREETING", "hello", "-f", ".env.production"}, &bytes.Buffer{}, &bytes.Buffer{})
+	before, _ := os.ReadFile(".env.production")
+
+	var stdout bytes.Buffer
+	if code := cli([]string{"rotate", "-f", ".env.production", "--dry-run"}, &stdout, &bytes.Buffer{}); code != 0 {
+		t.Fatalf("Expected exit 0, got %d", code)
+	}
+	want := ".env.production: would re-encrypt GREETING\n" +
+		".env.production: would replace DOTENV_PUBLIC_KEY_PRODUCTION\n" +
+		".env.keys: would replace DOTENV_PRIVATE_KEY_PRODUCTION\n"
+	if stdout.String() != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, stdout.String())
+	}
+	if after, _ := os.ReadFile(".env.production"); !bytes.Equal(before, after) {
+		t.Error("Expected --dry-run to write nothing")
+	}
+
+	stdout.Reset()
+	if code := cli([]string{"rotate", "-f", ".env.production"}, &stdout, &bytes.Buffer{}); code != 0 {
+		t.Fatalf("Expected exit 0, got %d", code)
+	}
+	if after, _ := os.ReadFile(".env.production"); bytes.Equal(before, after) || !strings.Contains(string(after), strings.TrimPrefix(strings.TrimSpace(stdout.String()), ".env.production: ")) {
+		t.Errorf("Expected the file under the printed public key, got %q", stdout.String())
+	}
+	stdout.Reset()
+	cli([]string{"-f", ".env.production"}, &stdout, &bytes.Buffer{})
+	if !strings.Contains(stdout.String(), "GREETING=hello") {
+		t.Errorf("Expected GREETING to decrypt with the new key, got %q", stdout.String())
+	}
+}
+
+func TestRotate_Errors(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+
+	if code := cli([]string{"rotate", "extra"}, &bytes.Buffer{}, &bytes.Buffer{}); code != 2 {
+		t.Errorf("Expected usage exit 2, got %d", code)
+	}
+	var stderr bytes.Buffer
+	if code := cli([]string{"rotate"}, &bytes.Buffer{}, &stderr); code != 1 || !strings.Contains(stderr.String(), "No key found") {
+		t.Errorf("Expected exit 1 without a key, got %d %q", code, stderr.String())
+	}
+}
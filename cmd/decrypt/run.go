@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// Swapped out in tests, which would otherwise be replaced by the child.
+var execve = syscall.Exec
+
+// Exec rather than fork: the scratch image has no shell to turn Environ's
+// output into an environment, and replacing ourselves keeps the secrets off
+// stdout and leaves the target as the container's main process.
+func runCommand(args []string, files *fileOptions, stderr io.Writer) int {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	supervised := flags.Bool("supervise", false, "fork instead of exec, forwarding signals and reaping zombies as PID 1")
+	var own fileOptions
+	own.register(flags)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	files.merge(&own)
+	if flags.NArg() == 0 {
+		fmt.Fprintln(stderr, "usage: decrypt run [--supervise] [--unset-keys] [-f file]... [--overload] [--convention nextjs] -- command [args...]")
+		return 2
+	}
+	loader, err := files.loader()
+	if err != nil {
+		fmt.Fprintf(stderr, "decrypt: %v\n", err)
+		return 2
+	}
+
+	env, err := loader.EnvironStrict()
+	if err != nil {
+		fmt.Fprintf(stderr, "decrypt: %v\n", err)
+		return 1
+	}
+	path, err := exec.LookPath(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "decrypt: %v\n", err)
+		return 127
+	}
+	if files.overload {
+		env = mergeEnv(env, os.Environ())
+	} else {
+		env = mergeEnv(os.Environ(), env)
+	}
+	if *supervised {
+		return supervise(path, flags.Args(), env, stderr)
+	}
+	err = execve(path, flags.Args(), env)
+	fmt.Fprintf(stderr, "decrypt: %s: %v\n", path, err)
+	return 126
+}
+
+// A variable in winners wins over the same name in rest. Like dotenvx run, the
+// process environment is the winner unless --overload.
+func mergeEnv(winners, rest []string) []string {
+	merged := append([]string{}, winners...)
+	seen := make(map[string]bool, len(winners))
+	for _, env := range winners {
+		seen[strings.SplitN(env, "=", 2)[0]] = true
+	}
+	for _, env := range rest {
+		if name := strings.SplitN(env, "=", 2)[0]; !seen[name] {
+			seen[name] = true
+			merged = append(merged, env)
+		}
+	}
+	return merged
+}
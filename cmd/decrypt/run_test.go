@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const testKeyHex = "2ff9d3716a37e630e0643447beac508a1e9963444d3ca00a6a22dbf2970dc03d"
+
+// "hello" encrypted to testKeyHex's public key
+const testCipher = "encrypted:BL8cvfR8496FAJV3dbdSZj/D6qlhOc3lAhuAB24AGp4WASPH8BBoe21T+T9jlO/M0GY03RZ94Etk7VPWIP21vh+YLGu0fWe2usFdTFs+/BnlsT8K8+V9Xte/yXA2NhrRxy3T7ygL"
+
+type execCall struct {
+	path string
+	argv []string
+	env  []string
+}
+
+func stubExec(t *testing.T) *[]execCall {
+	t.Helper()
+	var calls []execCall
+	original := execve
+	execve = func(path string, argv, env []string) error {
+		calls = append(calls, execCall{path, argv, env})
+		return errors.New("stubbed")
+	}
+	t.Cleanup(func() { execve = original })
+	return &calls
+}
+
+func TestRun_ExecsWithDecryptedEnv(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	calls := stubExec(t)
+
+	os.WriteFile(".env", []byte("GREETING="+testCipher+"\n"), 0644)
+	t.Setenv("DOTENV_PRIVATE_KEY", testKeyHex)
+
+	var stderr bytes.Buffer
+	if code := cli([]string{"run", "--", "/bin/sh", "-c", "true"}, os.Stdout, &stderr); code != 126 {
+		t.Errorf("Expected 126 once the stubbed exec returns, got %d", code)
+	}
+	if len(*calls) != 1 {
+		t.Fatalf("Expected one exec, got %d", len(*calls))
+	}
+	call := (*calls)[0]
+	if call.path != "/bin/sh" || !slices.Equal(call.argv, []string{"/bin/sh", "-c", "true"}) {
+		t.Errorf("Expected /bin/sh -c true, got %s %v", call.path, call.argv)
+	}
+	if !slices.Contains(call.env, "GREETING=hello") {
+		t.Errorf("Expected GREETING=hello in the child env, got %v", call.env)
+	}
+	if !slices.Contains(call.env, "DOTENV_PRIVATE_KEY="+testKeyHex) {
+		t.Error("Expected the process environment to be passed through")
+	}
+}
+
+func TestRun_UnsetKeys(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	calls := stubExec(t)
+
+	os.WriteFile(".env", []byte("GREETING="+testCipher+"\n"), 0644)
+	t.Setenv("DOTENV_PRIVATE_KEY", testKeyHex)
+
+	cli([]string{"run", "--unset-keys", "--", "/bin/sh", "-c", "true"}, os.Stdout, &bytes.Buffer{})
+	if len(*calls) != 1 || !slices.Contains((*calls)[0].env, "GREETING=hello") {
+		t.Fatalf("Expected one exec with GREETING decrypted, got %+v", *calls)
+	}
+	for _, env := range (*calls)[0].env {
+		if strings.HasPrefix(env, "DOTENV_PRIVATE_KEY") {
+			t.Errorf("Expected the key kept from the child, got %s", env)
+		}
+	}
+}
+
+func TestRun_LooksUpCommandInPath(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	calls := stubExec(t)
+
+	os.WriteFile(".env", []byte("PLAIN=value\n"), 0644)
+	t.Setenv("DOTENV_PRIVATE_KEY", testKeyHex)
+
+	cli([]string{"run", "sh", "-c", "true"}, os.Stdout, &bytes.Buffer{})
+	if len(*calls) != 1 || !strings.HasSuffix((*calls)[0].path, "/sh") || (*calls)[0].argv[0] != "sh" {
+		t.Errorf("Expected sh resolved through PATH with argv[0] kept, got %+v", *calls)
+	}
+}
+
+func TestRun_DecryptFailureDoesNotExec(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	calls := stubExec(t)
+
+	// A valid key, but not the one GREETING was encrypted to
+	os.WriteFile(".env", []byte("GREETING="+testCipher+"\n"), 0644)
+	t.Setenv("DOTENV_PRIVATE_KEY", "7d797417f477635f8753c5325d5a68552ab7048f46c518be7f0ae3bc245d3ab8")
+
+	var stderr bytes.Buffer
+	if code := cli([]string{"run", "--", "/bin/true"}, os.Stdout, &stderr); code != 1 {
+		t.Errorf("Expected exit 1, got %d", code)
+	}
+	if len(*calls) != 0 {
+		t.Errorf("Expected no exec, got %+v", *calls)
+	}
+	if !strings.Contains(stderr.String(), "GREETING") {
+		t.Errorf("Expected the error to name GREETING, got %q", stderr.String())
+	}
+}
+
+func TestRun_NoKeyDoesNotExec(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	calls := stubExec(t)
+	os.Unsetenv("DOTENV_PRIVATE_KEY")
+	os.Unsetenv("DOTENV_PRIVATE_KEY_PRODUCTION")
+
+	if code := cli([]string{"run", "--", "/bin/true"}, os.Stdout, &bytes.Buffer{}); code != 1 {
+		t.Errorf("Expected exit 1, got %d", code)
+	}
+	if len(*calls) != 0 {
+		t.Errorf("Expected no exec, got %+v", *calls)
+	}
+}
+
+func TestRun_MissingCommand(t *testing.T) {
+	calls := stubExec(t)
+
+	var stderr bytes.Buffer
+	if code := cli([]string{"run", "--"}, os.Stdout, &stderr); code != 2 {
+		t.Errorf("Expected usage exit 2, got %d", code)
+	}
+	if code := cli([]string{"run", "--bogus"}, os.Stdout, &stderr); code != 2 {
+		t.Errorf("Expected exit 2 for an unknown flag, got %d", code)
+	}
+	if len(*calls) != 0 {
+		t.Errorf("Expected no exec, got %+v", *calls)
+	}
+}
+
+func TestRun_CommandNotFound(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	calls := stubExec(t)
+
+	os.WriteFile(".env", []byte("PLAIN=value\n"), 0644)
+	t.Setenv("DOTENV_PRIVATE_KEY", testKeyHex)
+
+	if code := cli([]string{"run", "--", "no-such-command-here"}, os.Stdout, &bytes.Buffer{}); code != 127 {
+		t.Errorf("Expected exit 127, got %d", code)
+	}
+	if len(*calls) != 0 {
+		t.Errorf("Expected no exec, got %+v", *calls)
+	}
+}
+
+func TestMergeEnv_ProcessEnvWins(t *testing.T) {
+	merged := mergeEnv([]string{"A=process", "B=process"}, []string{"B=file", "C=file", "C=again"})
+	expected := []string{"A=process", "B=process", "C=file"}
+	if !slices.Equal(merged, expected) {
+		t.Errorf("Expected %v, got %v", expected, merged)
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/ericpollmann/dotenvx"
+)
+
+func setCommand(args []string, stderr io.Writer) int {
+	flags := flag.NewFlagSet("set", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	path := flags.String("f", ".env", "env file whose DOTENV_PUBLIC_KEY header to encrypt to")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) != 2 {
+		fmt.Fprintln(stderr, "usage: decrypt set NAME VALUE [-f .env.production]")
+		return 2
+	}
+
+	if err := dotenvx.SetEncrypted(*path, positional[0], positional[1]); err != nil {
+		fmt.Fprintf(stderr, "decrypt: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/ericpollmann/dotenvx"
+)
+
+const testHeader = `DOTENV_PUBLIC_KEY="020c5f23e6e02f087af380212814755c22f3d742b218666642d1dec184b7c6ae69"` + "\n"
+
+func TestSet_EncryptsIntoFile(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+
+	os.WriteFile(".env.staging", []byte(testHeader+"PLAIN=value\n"), 0644)
+
+	var stderr bytes.Buffer
+	if code := cli([]string{"set", "SECRET", "two words", "-f", ".env.staging"}, os.Stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit 0, got %d: %s", code, stderr.String())
+	}
+	vars, err := dotenvx.DecryptFile(".env.staging", testKeyHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vars) != 3 || vars[1].Name != "PLAIN" || vars[2].Name != "SECRET" || vars[2].Value != "two words" {
+		t.Errorf("Expected PLAIN kept and SECRET=two words appended, got %+v", vars)
+	}
+}
+
+func TestSet_DefaultsToDotEnv(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+
+	os.WriteFile(".env", []byte(testHeader), 0644)
+
+	if code := cli([]string{"set", "-f", ".env", "SECRET", "value"}, os.Stdout, &bytes.Buffer{}); code != 0 {
+		t.Fatalf("Expected exit 0, got %d", code)
+	}
+	if code := cli([]string{"set", "OTHER", "value"}, os.Stdout, &bytes.Buffer{}); code != 0 {
+		t.Fatalf("Expected exit 0, got %d", code)
+	}
+	vars, _ := dotenvx.DecryptFile(".env", testKeyHex)
+	if len(vars) != 3 {
+		t.Errorf("Expected the header and two secrets, got %+v", vars)
+	}
+}
+
+func TestSet_Errors(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+
+	os.WriteFile(".env", []byte("PLAIN=value\n"), 0644)
+
+	for _, args := range [][]string{{"set"}, {"set", "ONLY_NAME"}, {"set", "-bogus"}} {
+		if code := cli(args, os.Stdout, &bytes.Buffer{}); code != 2 {
+			t.Errorf("For %v: expected usage exit 2, got %d", args, code)
+		}
+	}
+	var stderr bytes.Buffer
+	if code := cli([]string{"set", "SECRET", "value"}, os.Stdout, &stderr); code != 1 {
+		t.Errorf("Expected exit 1 with no public key in .env, got %d", code)
+	}
+}
+
+func TestParseArgs_Interspersed(t *testing.T) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	path := flags.String("f", "", "")
+
+	positional, err := parseArgs(flags, []string{"NAME", "-f", "file", "VALUE", "--", "-f", "literal"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *path != "file" || !slices.Equal(positional, []string{"NAME", "VALUE", "-f", "literal"}) {
+		t.Errorf("Expected -f file and [NAME VALUE -f literal], got %q %v", *path, positional)
+	}
+}
+
+func TestSet_ThenGetKeepsDollar(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+
+	os.WriteFile(".env.staging", []byte(testHeader), 0644)
+	t.Setenv("DOTENV_PRIVATE_KEY_STAGING", testKeyHex)
+
+	if code := cli([]string{"set", "DB_PASS", `s3cr$t "x"`, "-f", ".env.staging"}, os.Stdout, &bytes.Buffer{}); code != 0 {
+		t.Fatalf("Expected exit 0, got %d", code)
+	}
+	var stdout bytes.Buffer
+	if code := cli([]string{"get", "-f", ".env.staging", "DB_PASS"}, &stdout, &bytes.Buffer{}); code != 0 || stdout.String() != "s3cr$t \"x\"\n" {
+		t.Errorf("Expected the value back with its $, got %d %q", code, stdout.String())
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+func supervise(path string, argv, env []string, stderr io.Writer) int {
+	fmt.Fprintln(stderr, "decrypt: --supervise is only supported on unix")
+	return 126
+}
@@ -0,0 +1,76 @@
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Fork rather than exec, for when we are PID 1: the kernel installs no default
+// handlers for PID 1, so a server that never asked for SIGTERM would only die
+// to SIGKILL, and orphans reparented to us would stay zombies with nobody to
+// wait for them. This is the part of tini the scratch image needs.
+func supervise(path string, argv, env []string, stderr io.Writer) int {
+	signals := make(chan os.Signal, 32)
+	signal.Notify(signals)
+	defer signal.Stop(signals)
+	// Notify drops what a full channel cannot take, so a burst of other signals
+	// must not cost us the child's exit. One pending is enough: reap drains
+	// every zombie there is.
+	exits := make(chan os.Signal, 1)
+	signal.Notify(exits, syscall.SIGCHLD)
+	defer signal.Stop(exits)
+
+	child, err := os.StartProcess(path, argv, &os.ProcAttr{
+		Env:   env,
+		Files: []*os.File{os.Stdin, os.Stdout, os.Stderr},
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "decrypt: %v\n", err)
+		return 126
+	}
+	for {
+		select {
+		case <-exits:
+			if status, exited := reap(child.Pid); exited {
+				return exitCode(status)
+			}
+		case sig := <-signals:
+			switch sig {
+			case syscall.SIGCHLD:
+				// exits has it
+			case syscall.SIGURG:
+				// The Go runtime preempts goroutines with it; it was never meant for the child
+			default:
+				child.Signal(sig)
+			}
+		}
+	}
+}
+
+// One SIGCHLD can stand for any number of exits, so drain every zombie we have,
+// ours or adopted, and report whether the one we started was among them.
+func reap(pid int) (status syscall.WaitStatus, exited bool) {
+	for {
+		var ws syscall.WaitStatus
+		reaped, err := syscall.Wait4(-1, &ws, syscall.WNOHANG, nil)
+		if err != nil || reaped <= 0 {
+			return status, exited
+		}
+		if reaped == pid && (ws.Exited() || ws.Signaled()) {
+			status, exited = ws, true
+		}
+	}
+}
+
+// Shell convention, so `docker stop` reads as 143 rather than a bare failure.
+func exitCode(status syscall.WaitStatus) int {
+	if status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return status.ExitStatus()
+}
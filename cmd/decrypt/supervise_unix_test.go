@@ -0,0 +1,85 @@
+//go:build unix
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func superviseInTempDir(t *testing.T, script string) int {
+	t.Helper()
+	os.WriteFile(".env", []byte("GREETING="+testCipher+"\n"), 0644)
+	t.Setenv("DOTENV_PRIVATE_KEY", testKeyHex)
+	return cli([]string{"run", "--supervise", "--", "/bin/sh", "-c", script}, os.Stdout, &bytes.Buffer{})
+}
+
+func TestSupervise_PassesExitCodeAndEnv(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+
+	if code := superviseInTempDir(t, `test "$GREETING" = hello || exit 9; exit 3`); code != 3 {
+		t.Errorf("Expected the child's exit code 3, got %d", code)
+	}
+}
+
+func TestSupervise_KilledChildIs128PlusSignal(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+
+	if code := superviseInTempDir(t, `kill -TERM $$`); code != 128+int(syscall.SIGTERM) {
+		t.Errorf("Expected %d, got %d", 128+int(syscall.SIGTERM), code)
+	}
+}
+
+// What `docker stop` does to PID 1: the child must see the SIGTERM we received.
+func TestSupervise_ForwardsSignals(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+
+	go func() {
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			if _, err := os.Stat("ready"); err == nil {
+				syscall.Kill(os.Getpid(), syscall.SIGTERM)
+				return
+			}
+		}
+	}()
+
+	code := superviseInTempDir(t, `trap 'exit 7' TERM; touch ready; while :; do sleep 0.05; done`)
+	if code != 7 {
+		t.Errorf("Expected the child's TERM trap to exit 7, got %d", code)
+	}
+}
+
+func TestSupervise_StartFailure(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+
+	os.WriteFile("not-executable", []byte("#!/bin/sh\n"), 0644)
+	if code := supervise("./not-executable", []string{"not-executable"}, nil, &bytes.Buffer{}); code != 126 {
+		t.Errorf("Expected 126, got %d", code)
+	}
+}
+
+// Orphans only reach us as PID 1, but any zombie child stands in for them.
+func TestReap_DrainsEveryZombie(t *testing.T) {
+	attr := &os.ProcAttr{}
+	orphan, err := os.StartProcess("/bin/sh", []string{"sh", "-c", "exit 0"}, attr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	child, err := os.StartProcess("/bin/sh", []string{"sh", "-c", "exit 4"}, attr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	status, exited := reap(child.Pid)
+	if !exited || exitCode(status) != 4 {
+		t.Errorf("Expected the child to have exited 4, got %v %d", exited, exitCode(status))
+	}
+	var ws syscall.WaitStatus
+	if _, err := syscall.Wait4(orphan.Pid, &ws, syscall.WNOHANG, nil); err != syscall.ECHILD {
+		t.Errorf("Expected the other zombie to have been reaped too, got %v", err)
+	}
+}
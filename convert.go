@@ -0,0 +1,158 @@
+package dotenvx
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Which variables EncryptInPlace and DecryptInPlace touch, as dotenvx's
+// -k and -ek take them: glob patterns matched against the whole name, * and ?
+// and [...] as in path.Match. No Include means every variable; Exclude wins.
+type KeyFilter struct {
+	Include []string
+	Exclude []string
+}
+
+func (f KeyFilter) validate() error {
+	for _, pattern := range slices.Concat(f.Include, f.Exclude) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+func (f KeyFilter) matches(name string) bool {
+	match := func(pattern string) bool {
+		ok, _ := path.Match(pattern, name)
+		return ok
+	}
+	return (len(f.Include) == 0 || slices.ContainsFunc(f.Include, match)) && !slices.ContainsFunc(f.Exclude, match)
+}
+
+// Encrypts every plaintext value in the file at path that filter matches to the
+// file's own public key, leaving comments, order and quoting as they were, and
+// returns the names encrypted. A file without a header gets a new keypair
+// first, as GenerateKeyPair makes it. Values are encrypted as written, ${...}
+// and all, to expand when the file is decrypted; empty ones are left alone,
+// having nothing to hide and no ciphertext eciesjs would accept.
+func EncryptInPlace(path string, filter KeyFilter) ([]string, error) {
+	if err := filter.validate(); err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	publicKeyHex, ok := publicKeyOf(parseEnv(string(content)))
+	if !ok {
+		if publicKeyHex, err = GenerateKeyPair(path); err != nil {
+			return nil, err
+		}
+		if content, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	var names []string
+	doc := ParseDocument(content)
+	for i, node := range doc.nodes {
+		if node.Kind != AssignmentNode || isPublicKeyVar(node.Name) || node.Value == "" || strings.HasPrefix(node.Value, encryptedPrefix) || !filter.matches(node.Name) {
+			continue
+		}
+		encrypted, err := EncryptValue(publicKeyHex, node.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		doc.setValue(i, encrypted)
+		names = append(names, node.Name)
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	return names, writeFileAtomic(path, doc.Bytes(), info.Mode().Perm())
+}
+
+// The inverse of EncryptInPlace, for editing a file by hand: decrypts every
+// encrypted value filter matches with the file's key, found the way Getenv
+// finds it, and writes the plaintext back in its place. The header stays, so
+// EncryptInPlace can put the values back under the same key. Nothing is written
+// unless every value decrypts.
+func DecryptInPlace(path string, filter KeyFilter) ([]string, error) {
+	if err := filter.validate(); err != nil {
+		return nil, err
+	}
+	key, _, err := fileKey(path)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := parsePrivateKeys(key.keyHex)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", key.varName, err)
+	}
+	defer zeroKeys([]EnvFile{{Keys: keys}})
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPublicKey(parseEnv(string(content)), keys); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var names []string
+	doc := ParseDocument(content)
+	for i, node := range doc.nodes {
+		if node.Kind != AssignmentNode || !strings.HasPrefix(node.Value, encryptedPrefix) || !filter.matches(node.Name) {
+			continue
+		}
+		plain, err := decryptSecretStrict(keys, node.Value[len(encryptedPrefix):])
+		if err != nil {
+			return nil, &DecryptError{path, node.Line, node.Name, err}
+		}
+		doc.setValue(i, plain)
+		names = append(names, node.Name)
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	return names, writeFileAtomic(path, doc.Bytes(), info.Mode().Perm())
+}
+
+// The key for the file at path as Getenv would find it, by the variable named
+// after the file, and the .env.keys it is or would be kept in: beside the file,
+// unless DOTENV_KEYS_PATH names one, which is taken as given.
+func fileKey(path string) (key keyCandidate, keysPath string, err error) {
+	l := New(WithDir(filepath.Dir(path)))
+	keysPath = l.path(keysFile)
+	if explicit := os.Getenv(keysPathVar); explicit != "" {
+		l, keysPath = New(), explicit
+	}
+	key.varName = keyVarForEnvFile(filepath.Base(path))
+	if key.varName == "" {
+		return key, "", fmt.Errorf("%s: not a .env or .env.* file", path)
+	}
+	for _, entry := range l.keyEntries() {
+		if entry.varName == key.varName {
+			key = entry
+			if entry.source != "environment" {
+				keysPath = entry.source
+			}
+		}
+	}
+	if key.keyHex == "" {
+		return key, "", fmt.Errorf("%s: %w: no %s", path, ErrNoKey, key.varName)
+	}
+	return key, keysPath, nil
+}
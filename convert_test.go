@@ -0,0 +1,177 @@
+package dotenvx
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestKeyFilter_Matches(t *testing.T) {
+	for _, tt := range []struct {
+		filter KeyFilter
+		name   string
+		want   bool
+	}{
+		{KeyFilter{}, "ANY", true},
+		{KeyFilter{Include: []string{"DB_*"}}, "DB_PASSWORD", true},
+		{KeyFilter{Include: []string{"DB_*"}}, "API_KEY", false},
+		{KeyFilter{Include: []string{"DB_*", "API_KEY"}}, "API_KEY", true},
+		{KeyFilter{Include: []string{"DB_?"}}, "DB_PASSWORD", false},
+		{KeyFilter{Exclude: []string{"*_URL"}}, "DB_URL", false},
+		{KeyFilter{Include: []string{"DB_*"}, Exclude: []string{"DB_URL"}}, "DB_URL", false},
+		{KeyFilter{Include: []string{"[AB]_KEY"}}, "B_KEY", true},
+	} {
+		if got := tt.filter.matches(tt.name); got != tt.want {
+			t.Errorf("%+v on %s: expected %t", tt.filter, tt.name, tt.want)
+		}
+	}
+	if err := (KeyFilter{Exclude: []string{"[unclosed"}}).validate(); err == nil {
+		t.Error("Expected a malformed pattern rejected")
+	}
+}
+
+func TestEncryptInPlace(t *testing.T) {
+	clearEnvKeys()
+	fixture, _ := embedded.ReadFile(".env")
+	plain := "# database\nexport DB_PASSWORD='hunter2' # inline\nDB_URL=\"postgres://u:${DB_PASSWORD}@h\"\nAPI_KEY=abc\nEMPTY=\n"
+	dir := writeFiles(t, map[string]string{".env": string(fixture) + plain})
+	path := filepath.Join(dir, ".env")
+	os.Chmod(path, 0640)
+
+	names, err := EncryptInPlace(path, KeyFilter{Exclude: []string{"API_*"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"DB_PASSWORD", "DB_URL"}; !slices.Equal(names, want) {
+		t.Errorf("Expected %v encrypted, got %v", want, names)
+	}
+	content, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(content), string(fixture)+"# database\nexport DB_PASSWORD='encrypted:") ||
+		!strings.Contains(string(content), "' # inline\nDB_URL=\"encrypted:") || !strings.HasSuffix(string(content), "\nAPI_KEY=abc\nEMPTY=\n") {
+		t.Errorf("Expected only the chosen values rewritten, got:\n%s", content)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0640 {
+		t.Errorf("Expected the mode kept, got %v", info.Mode())
+	}
+
+	vars, err := DecryptFile(path, testKeyHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, v := range vars {
+		got[v.Name] = v.Value
+	}
+	if got["GREETING"] != "hello" || got["DB_PASSWORD"] != "hunter2" || got["DB_URL"] != "postgres://u:hunter2@h" || got["API_KEY"] != "abc" || got["EMPTY"] != "" {
+		t.Errorf("Expected the values to decrypt as before, got %v", got)
+	}
+
+	before, _ := os.ReadFile(path)
+	if names, err := EncryptInPlace(path, KeyFilter{Include: []string{"DB_*"}}); err != nil || names != nil {
+		t.Errorf("Expected nothing left to encrypt, got %v %v", names, err)
+	}
+	if after, _ := os.ReadFile(path); string(after) != string(before) {
+		t.Error("Expected the file untouched")
+	}
+}
+
+func TestEncryptInPlace_CreatesKeyPair(t *testing.T) {
+	clearEnvKeys()
+	dir := writeFiles(t, map[string]string{".env.staging": "TOKEN=plain\n"})
+	path := filepath.Join(dir, ".env.staging")
+
+	if names, err := EncryptInPlace(path, KeyFilter{}); err != nil || !slices.Equal(names, []string{"TOKEN"}) {
+		t.Fatalf("Expected TOKEN encrypted, got %v %v", names, err)
+	}
+	keys, _ := os.ReadFile(filepath.Join(dir, ".env.keys"))
+	keyHex, ok := ParseDocument(keys).Get("DOTENV_PRIVATE_KEY_STAGING")
+	if !ok {
+		t.Fatalf("Expected a key written to .env.keys, got:\n%s", keys)
+	}
+	if vars, err := DecryptFile(path, keyHex); err != nil || vars[len(vars)-1].Value != "plain" {
+		t.Errorf("Expected TOKEN to decrypt with the new key, got %+v %v", vars, err)
+	}
+}
+
+func TestDecryptInPlace(t *testing.T) {
+	clearEnvKeys()
+	fixture, _ := embedded.ReadFile(".env.production")
+	dir := writeFiles(t, map[string]string{
+		".env.production": string(fixture),
+		".env.keys":       "DOTENV_PRIVATE_KEY_PRODUCTION=" + prodKeyHex + "\n",
+	})
+	path := filepath.Join(dir, ".env.production")
+	os.WriteFile(path, append(fixture, "QUOTE=\"say \\\"hi\\\"\"\nKEEP=${GREETING}\n"...), 0644)
+	if _, err := EncryptInPlace(path, KeyFilter{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if names, err := DecryptInPlace(path, KeyFilter{Exclude: []string{"KEEP"}}); err != nil || !slices.Equal(names, []string{"GREETING", "QUOTE"}) {
+		t.Fatalf("Expected GREETING and QUOTE decrypted, got %v %v", names, err)
+	}
+	content, _ := os.ReadFile(path)
+	doc := ParseDocument(content)
+	if got, _ := doc.Get("GREETING"); got != "world" || !strings.Contains(string(content), "\nGREETING=world\n") {
+		t.Errorf("Expected GREETING=world back in the file, got:\n%s", content)
+	}
+	if got, _ := doc.Get("QUOTE"); got != `say "hi"` {
+		t.Errorf("Expected QUOTE to read back, got %q", got)
+	}
+	if got, _ := doc.Get("KEEP"); !strings.HasPrefix(got, encryptedPrefix) {
+		t.Errorf("Expected KEEP left encrypted, got %q", got)
+	}
+	if !strings.HasPrefix(string(content), publicKeyHeader) {
+		t.Error("Expected the header kept")
+	}
+
+	if _, err := EncryptInPlace(path, KeyFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	if vars, err := DecryptFile(path, prodKeyHex); err != nil || vars[len(vars)-1].Value != "world" {
+		t.Errorf("Expected the round trip under the same key, got %+v %v", vars, err)
+	}
+}
+
+func TestDecryptInPlace_Errors(t *testing.T) {
+	clearEnvKeys()
+	fixture, _ := embedded.ReadFile(".env.production")
+	dir := writeFiles(t, map[string]string{".env.production": string(fixture), ".env.other": "A=1\n"})
+	path := filepath.Join(dir, ".env.production")
+
+	if _, err := DecryptInPlace(path, KeyFilter{}); !errors.Is(err, ErrNoKey) {
+		t.Errorf("Expected ErrNoKey, got %v", err)
+	}
+	if _, err := DecryptInPlace(filepath.Join(dir, "config.env"), KeyFilter{}); err == nil {
+		t.Error("Expected a file not named .env* rejected")
+	}
+	os.WriteFile(filepath.Join(dir, ".env.keys"), []byte("DOTENV_PRIVATE_KEY_PRODUCTION="+testKeyHex+"\n"), 0600)
+	if _, err := DecryptInPlace(path, KeyFilter{}); !errors.Is(err, ErrKeyMismatch) {
+		t.Errorf("Expected ErrKeyMismatch, got %v", err)
+	}
+	if _, err := DecryptInPlace(path, KeyFilter{Include: []string{"["}}); err == nil {
+		t.Error("Expected a malformed pattern rejected")
+	}
+	if content, _ := os.ReadFile(path); string(content) != string(fixture) {
+		t.Error("Expected the file untouched")
+	}
+}
+
+func TestDecryptInPlace_KeysPathFromWorkingDirectory(t *testing.T) {
+	clearEnvKeys()
+	fixture, _ := embedded.ReadFile(".env.production")
+	defer os.Chdir(inTempDir(t))
+	os.Mkdir("config", 0755)
+	os.WriteFile("config/.env.production", fixture, 0644)
+	os.WriteFile("config/keys", []byte("DOTENV_PRIVATE_KEY_PRODUCTION="+prodKeyHex+"\n"), 0600)
+	t.Setenv("DOTENV_KEYS_PATH", "config/keys")
+
+	if names, err := DecryptInPlace("config/.env.production", KeyFilter{}); err != nil || !slices.Equal(names, []string{"GREETING"}) {
+		t.Errorf("Expected DOTENV_KEYS_PATH read from the working directory, got %v %v", names, err)
+	}
+	if _, keysPath, err := fileKey("config/.env.production"); err != nil || keysPath != "config/keys" {
+		t.Errorf("Expected config/keys, got %q %v", keysPath, err)
+	}
+}
@@ -1,28 +1,74 @@
 package dotenvx
 
 import (
-	"bufio"
 	"encoding/base64"
+	"errors"
 	"fmt"
-	"os"
+	"io"
+	"io/fs"
+	"slices"
 	"sort"
 	"strings"
 
 	ecies "github.com/ecies/go/v2"
 )
 
+// Where the package-level functions log key discovery and decryption; a Loader
+// takes WithLogger instead. Events name variables and files, never values.
+var Logger = discardLogger
+
+// When true, a value that fails to decrypt reads as "" in Getenv and Environ
+// and the rest of the file is still served, as before strict snapshots. Off,
+// one such value makes the whole file unavailable, so a wrong key cannot pass
+// for a set of empty secrets.
+var Lenient bool
+
+// Logs at debug level to stderr while Logger is left as it is, for those who
+// set this before Logger existed.
 var (
 	Debug bool
 )
 
+// What finding a key or a variable can fail with, for errors.Is; the messages are the ones
+// this package has always returned, so logs read the same.
+var (
+	ErrNoKey          = errors.New("No key found")
+	ErrAmbiguousKey   = errors.New("Ambiguous private key")
+	ErrInvalidKey     = errors.New("Invalid private key")
+	ErrNoMatchingFile = errors.New("No valid file/key combination found")
+	ErrNotFound       = errors.New("Variable not found")
+	ErrKeyMismatch    = errors.New("Key does not match the file's public key")
+)
+
+// A value that is in the file but none of the keys decrypts: a wrong key, or a
+// ciphertext that was cut or tampered with. Err says which.
+type DecryptError struct {
+	Path string
+	Line int
+	Name string
+	Err  error
+}
+
+func (e *DecryptError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("line %d: %s: %v", e.Line, e.Name, e.Err)
+	}
+	return fmt.Sprintf("%s:%d: %s: %v", e.Path, e.Line, e.Name, e.Err)
+}
+
+func (e *DecryptError) Unwrap() error { return e.Err }
+
 type EnvFile struct {
 	Path string
-	Key  *ecies.PrivateKey
+	Keys []*ecies.PrivateKey
 }
 
+// Encrypted is set when the value was encrypted in the file, or expands one in
+// the same file that was; such a value prints and logs redacted.
 type EnvVar struct {
-	Name  string
-	Value string
+	Name      string
+	Value     string
+	Encrypted bool
 }
 
 const keyVar = "DOTENV_PRIVATE_KEY"
@@ -31,6 +77,50 @@ type keyCandidate struct {
 	varName  string
 	fileName string
 	keyHex   string
+	source   string
+}
+
+const keysPathVar = "DOTENV_KEYS_PATH"
+
+// Keys exported into the process come first and shadow the same name in
+// .env.keys, so a laptop's file never overrides what a deployment injected.
+func (l *Loader) keyEntries() (entries []keyCandidate) {
+	seen := map[string]bool{}
+	lookupEnv := l.lookupEnv()
+	for _, env := range l.environ() {
+		if !strings.HasPrefix(env, keyVar) {
+			continue
+		}
+		parts := strings.SplitN(env, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			continue
+		}
+		seen[parts[0]] = true
+		entries = append(entries, keyCandidate{varName: parts[0], keyHex: parts[1], source: "environment"})
+	}
+
+	path, explicit := lookupEnv(keysPathVar)
+	if !explicit || path == "" {
+		path, explicit = keysFile, false
+	}
+	path = l.path(path)
+	content, err := l.readFile(path)
+	if err != nil {
+		if explicit || !errors.Is(err, fs.ErrNotExist) {
+			l.log().Warn("keys file unreadable", "file", path, "error", err)
+		}
+		return entries
+	}
+	parsed := parseEnv(string(content))
+	clear(content)
+	for _, entry := range parsed {
+		if !strings.HasPrefix(entry.name, keyVar) || entry.value == "" || seen[entry.name] {
+			continue
+		}
+		seen[entry.name] = true
+		entries = append(entries, keyCandidate{varName: entry.name, keyHex: entry.value, source: path})
+	}
+	return entries
 }
 
 // Mirrors dotenvx's own convention: DOTENV_PRIVATE_KEY_QA_TEST -> .env.qa.test
@@ -46,14 +136,12 @@ func envFileForKeyVar(varName string) string {
 
 // A first-match scan of os.Environ picks by setenv insertion order, so adding or
 // renaming an unrelated key silently switches which file gets decrypted.
-func chooseCandidate(candidates []keyCandidate) (*keyCandidate, error) {
+func (l *Loader) chooseCandidate(candidates []keyCandidate) (*keyCandidate, error) {
 	sort.Slice(candidates, func(i, j int) bool { return candidates[i].varName < candidates[j].varName })
 
 	for i := range candidates {
 		if candidates[i].varName == keyVar {
-			if Debug && len(candidates) > 1 {
-				fmt.Printf("Multiple private keys match a file; %s wins over the suffixed ones\n", keyVar)
-			}
+			l.log().Debug("key selected", "key_var", keyVar, "file", candidates[i].fileName, "candidates", len(candidates))
 			return &candidates[i], nil
 		}
 	}
@@ -61,222 +149,418 @@ func chooseCandidate(candidates []keyCandidate) (*keyCandidate, error) {
 	case 0:
 		return nil, nil
 	case 1:
+		l.log().Debug("key selected", "key_var", candidates[0].varName, "file", candidates[0].fileName, "candidates", 1)
 		return &candidates[0], nil
 	}
 
+	// The names alone are a tie; a key that fits only its own file's header is not
+	matching := slices.DeleteFunc(slices.Clone(candidates), func(c keyCandidate) bool { return !l.matchesFile(c) })
+	if len(matching) == 1 {
+		l.log().Debug("key selected", "key_var", matching[0].varName, "file", matching[0].fileName, "candidates", len(candidates), "by", "public key")
+		return &matching[0], nil
+	}
+
 	names := make([]string, 0, len(candidates))
 	for _, c := range candidates {
 		names = append(names, c.varName+" -> "+c.fileName)
 	}
-	return nil, fmt.Errorf("Ambiguous private key: %s, and no %s for .env to break the tie",
-		strings.Join(names, ", "), keyVar)
+	return nil, fmt.Errorf("%w: %s, and no %s for .env to break the tie",
+		ErrAmbiguousKey, strings.Join(names, ", "), keyVar)
 }
 
-func getEnvFile() (envFile EnvFile, err error) {
-	if Debug {
-		fmt.Println("Checking for private key in environment")
+func (l *Loader) matchesFile(c keyCandidate) bool {
+	content, err := l.readFile(c.fileName)
+	if err != nil {
+		return false
+	}
+	publicKeyHex, ok := publicKeyOf(parseEnv(string(content)))
+	if !ok {
+		return false
 	}
+	keys, err := parsePrivateKeys(c.keyHex)
+	if err != nil {
+		return false
+	}
+	defer zeroKeys([]EnvFile{{Keys: keys}})
+	return keysMatch(keys, publicKeyHex)
+}
 
-	keysInEnv := 0
-	var candidates []keyCandidate
-	for _, env := range os.Environ() {
-		if !strings.HasPrefix(env, keyVar) {
-			continue
-		}
-		parts := strings.SplitN(env, "=", 2)
-		if len(parts) != 2 || parts[1] == "" {
-			continue
-		}
-		keysInEnv++
+func (l *Loader) getEnvFile() (envFile EnvFile, err error) {
+	if len(l.keys) > 0 {
+		return l.explicitEnvFile()
+	}
+	l.log().Debug("key discovery", "keys_file", keysFile)
 
-		candidate := keyCandidate{parts[0], envFileForKeyVar(parts[0]), parts[1]}
-		if Debug {
-			fmt.Printf("Found key %s and file %s\n", candidate.varName, candidate.fileName)
-		}
-		if _, err := os.Stat(candidate.fileName); err != nil {
-			if Debug {
-				fmt.Printf("Unable to open: %s\n", candidate.fileName)
-			}
+	entries := l.keyEntries()
+	var candidates []keyCandidate
+	for _, candidate := range entries {
+		candidate.fileName = l.path(envFileForKeyVar(candidate.varName))
+		if _, err := l.stat(candidate.fileName); err != nil {
+			l.log().Debug("key found, file missing", "key_var", candidate.varName, "source", candidate.source, "file", candidate.fileName)
 			continue
 		}
+		l.log().Debug("key found", "key_var", candidate.varName, "source", candidate.source, "file", candidate.fileName)
 		candidates = append(candidates, candidate)
 	}
 
-	chosen, err := chooseCandidate(candidates)
+	chosen, err := l.chooseCandidate(candidates)
 	if err != nil {
-		if Debug {
-			fmt.Println(err)
-		}
+		l.log().Warn("key ambiguous", "error", err)
 		return envFile, err
 	}
 	if chosen != nil {
-		if privateKey, err := ecies.NewPrivateKeyFromHex(chosen.keyHex); err == nil {
-			return EnvFile{chosen.fileName, privateKey}, nil
-		} else if Debug {
-			fmt.Println("Invalid key format")
+		keys, err := parsePrivateKeys(chosen.keyHex)
+		if err != nil {
+			l.log().Warn("key invalid", "key_var", chosen.varName, "error", err)
+			return envFile, fmt.Errorf("%s: %w", chosen.varName, err)
 		}
+		return EnvFile{chosen.fileName, keys}, nil
 	}
 
-	// No valid file/key combination found
-	if keysInEnv == 0 {
-		if Debug {
-			fmt.Println("No key found")
-		}
-		err = fmt.Errorf("No key found")
-	} else {
-		err = fmt.Errorf("No valid file/key combination found")
+	if len(entries) == 0 {
+		l.log().Debug("no key found")
+		return envFile, ErrNoKey
 	}
+	return envFile, ErrNoMatchingFile
+}
+
+// WithKeys alone skips choosing by name: the keys are for .env, as dotenvx
+// would have it.
+func (l *Loader) explicitEnvFile() (EnvFile, error) {
+	path := l.path(".env")
+	keys, err := parsePrivateKeys(strings.Join(l.keys, ","))
+	if err != nil {
+		l.log().Warn("key invalid", "file", path, "error", err)
+		return EnvFile{}, err
+	}
+	return EnvFile{path, keys}, nil
+}
 
-	return envFile, err
+// dotenvx allows several comma-separated keys in one variable so a file stays
+// readable while its values are being rotated to a new key.
+func parsePrivateKeys(keyHexList string) ([]*ecies.PrivateKey, error) {
+	var keys []*ecies.PrivateKey
+	for _, keyHex := range strings.Split(keyHexList, ",") {
+		if keyHex = strings.TrimSpace(keyHex); keyHex == "" {
+			continue
+		}
+		privateKey, err := ecies.NewPrivateKeyFromHex(keyHex)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+		}
+		keys = append(keys, privateKey)
+	}
+	if len(keys) == 0 {
+		return nil, ErrNoKey
+	}
+	return keys, nil
 }
 
 const encryptedPrefix = "encrypted:"
 
-func decryptSecret(privateKey *ecies.PrivateKey, base64ciper string) string {
-	cipherBytes, _ := base64.StdEncoding.DecodeString(base64ciper)
-	plainBytes, _ := ecies.Decrypt(privateKey, cipherBytes)
-	return string(plainBytes)
+func decryptSecret(keys []*ecies.PrivateKey, base64ciper string) string {
+	plain, _ := decryptSecretStrict(keys, base64ciper)
+	return plain
 }
 
 // Wrong-key decryption fails the AEAD tag check rather than returning garbage,
-// so propagating the error is what separates it from a genuinely empty value.
-func decryptSecretStrict(privateKey *ecies.PrivateKey, base64cipher string) (string, error) {
+// so propagating the error is what separates it from a genuinely empty value,
+// and is also what lets each key be tried in turn.
+func decryptSecretStrict(keys []*ecies.PrivateKey, base64cipher string) (string, error) {
+	return decryptInto(nil, keys, base64cipher)
+}
+
+// decryptSecretStrict straight into a when it is not nil, so the plaintext is
+// never a heap string; ecies's own buffer is zeroed either way.
+func decryptInto(a *arena, keys []*ecies.PrivateKey, base64cipher string) (string, error) {
 	cipherBytes, err := base64.StdEncoding.DecodeString(base64cipher)
 	if err != nil {
 		return "", fmt.Errorf("base64: %w", err)
 	}
-	plainBytes, err := ecies.Decrypt(privateKey, cipherBytes)
+	err = ErrNoKey
+	for _, privateKey := range keys {
+		var plainBytes []byte
+		if plainBytes, err = ecies.Decrypt(privateKey, cipherBytes); err != nil {
+			continue
+		}
+		if len(plainBytes) == 0 && len(cipherBytes) > 0 {
+			return "", fmt.Errorf("decrypted to an empty value")
+		}
+		var plain string
+		if a != nil {
+			plain = a.addBytes(plainBytes)
+		} else {
+			plain = string(plainBytes)
+		}
+		clear(plainBytes)
+		return plain, nil
+	}
+	if len(keys) > 1 {
+		return "", fmt.Errorf("none of %d keys could decrypt it: %w", len(keys), err)
+	}
+	return "", err
+}
+
+func (l *Loader) logDecrypt(path string, entry envEntry, err error) {
 	if err != nil {
-		return "", err
+		l.log().Warn("decrypt failed", "file", path, "line", entry.line, "var", entry.name, "error", err)
+	} else {
+		l.log().Debug("decrypted", "file", path, "line", entry.line, "var", entry.name)
 	}
-	if len(plainBytes) == 0 && len(cipherBytes) > 0 {
-		return "", fmt.Errorf("decrypted to an empty value")
+}
+
+// Decrypts every value it can. failed is nil when all decrypted; otherwise it
+// lines up with vars and holds a *DecryptError for each value left "", so the
+// caller decides whether one bad value spoils the rest. With an arena the
+// values are decrypted into it.
+func (l *Loader) readVars(path string, keys []*ecies.PrivateKey, lookupEnv func(string) (string, bool), a *arena) (vars []EnvVar, failed []error, err error) {
+	content, err := l.readFile(path)
+	if err != nil {
+		l.log().Warn("file unreadable", "file", path, "error", err)
+		return nil, nil, err
 	}
-	return string(plainBytes), nil
+	return l.decodeVars(path, content, keys, lookupEnv, a)
 }
 
-// ok is false for blank lines, comments, and -- when name is non-empty --
-// every variable except that one.
-func splitEnvLine(line, name string) (varName, value string, ok bool) {
-	offset := strings.Index(line, "=")
-	if offset <= 0 || line[0] == '#' {
-		return "", "", false
+// readVars once the content is in hand; path only labels logs and errors.
+func (l *Loader) decodeVars(path string, content []byte, keys []*ecies.PrivateKey, lookupEnv func(string) (string, bool), a *arena) (vars []EnvVar, failed []error, err error) {
+	entries := parseEnv(string(content))
+	if err := checkPublicKey(entries, keys); err != nil {
+		l.log().Warn("key mismatch", "file", path, "error", err)
+		if path == "" {
+			return nil, nil, err
+		}
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
 	}
-	varName = strings.TrimPrefix(line[:offset], "export ")
-	if name != "" && varName != name {
-		return "", "", false
+	for i, entry := range entries {
+		if !strings.HasPrefix(entry.value, encryptedPrefix) {
+			continue
+		}
+		var err error
+		entries[i].encrypted = true
+		entries[i].value, err = decryptInto(a, keys, entry.value[len(encryptedPrefix):])
+		l.logDecrypt(path, entry, err)
+		if err != nil {
+			if failed == nil {
+				failed = make([]error, len(entries))
+			}
+			failed[i] = &DecryptError{path, entry.line, entry.name, err}
+		}
 	}
-	value = strings.TrimSpace(line[offset+1:])
-	if (strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`)) ||
-		(strings.HasPrefix(value, `'`) && strings.HasSuffix(value, `'`)) {
-		value = value[1 : len(value)-1]
+	if err := expandEntries(entries, lookupEnv); err != nil {
+		l.log().Warn("expansion failed", "file", path, "error", err)
+		if path == "" {
+			return nil, nil, err
+		}
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
 	}
-	return varName, value, true
+
+	vars = make([]EnvVar, 0, len(entries))
+	for _, entry := range entries {
+		vars = append(vars, EnvVar{entry.name, entry.value, entry.encrypted})
+	}
+	return vars, failed, nil
 }
 
-func parseEnvVar(line string, privateKey *ecies.PrivateKey, name string) EnvVar {
-	varName, value, ok := splitEnvLine(line, name)
-	if !ok {
-		return EnvVar{}
+// A wrong key would otherwise only show as every value failing its AEAD check.
+// Files with nothing to decrypt, or no DOTENV_PUBLIC_KEY* header, pass.
+func checkPublicKey(entries []envEntry, keys []*ecies.PrivateKey) error {
+	publicKeyHex, ok := publicKeyOf(entries)
+	if !ok || len(keys) == 0 || keysMatch(keys, publicKeyHex) {
+		return nil
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.value, encryptedPrefix) {
+			return ErrKeyMismatch
+		}
 	}
-	if strings.HasPrefix(value, encryptedPrefix) {
-		value = decryptSecret(privateKey, value[len(encryptedPrefix):])
+	return nil
+}
+
+func firstFailure(failed []error) error {
+	for _, err := range failed {
+		if err != nil {
+			return err
+		}
 	}
-	return EnvVar{varName, value}
+	return nil
 }
 
-func getEnvVars(envFile *EnvFile, name string) (vars []EnvVar, err error) {
-	file, err := os.Open(envFile.Path)
+func (l *Loader) getEnvVars(envFile *EnvFile, name string) (vars []EnvVar, err error) {
+	all, _, err := l.readVars(envFile.Path, envFile.Keys, l.lookupEnv(), nil)
 	if err != nil {
-		if Debug {
-			fmt.Printf("Unable to open %s: %v\n", envFile.Path, err)
-		}
 		return []EnvVar{}, err
 	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		envVar := parseEnvVar(scanner.Text(), envFile.Key, name)
-		if envVar.Name == "" {
-			continue
+	for _, v := range all {
+		if name == "" || v.Name == name {
+			vars = append(vars, v)
 		}
-		vars = append(vars, envVar)
 	}
-	return vars, scanner.Err()
+	return vars, nil
 }
 
 // Unlike Getenv and Environ, which pick a file by scanning the environment for
-// any usable DOTENV_PRIVATE_KEY* and silently yield "" for whatever they cannot
-// decrypt, this names one file and one key and fails on the first value that
-// does not decrypt. Callers holding secrets they must not run without want this
-// one.
+// any usable DOTENV_PRIVATE_KEY*, this names one file and one key -- or a
+// comma-separated list, as in DOTENV_PRIVATE_KEY -- and fails on the first
+// value none of them decrypts. Callers holding secrets they must not run
+// without want this one.
 func DecryptFile(path string, privateKeyHex string) ([]EnvVar, error) {
-	privateKey, err := ecies.NewPrivateKeyFromHex(privateKeyHex)
+	return defaultLoader.DecryptFile(path, privateKeyHex)
+}
+
+// DecryptFile's strictness for content that never was a file on disk: a
+// []byte through bytes.NewReader, or a download. Keys are optional, each may be
+// a comma-separated list, and an encrypted value none of them decrypts is a
+// *DecryptError without a Path.
+func Decrypt(r io.Reader, privateKeyHex ...string) ([]EnvVar, error) {
+	return defaultLoader.Decrypt(r, privateKeyHex...)
+}
+
+func (l *Loader) Decrypt(r io.Reader, privateKeyHex ...string) ([]EnvVar, error) {
+	content, err := io.ReadAll(r)
 	if err != nil {
-		return nil, fmt.Errorf("%s: private key: %w", path, err)
+		return nil, err
 	}
+	return l.decryptContent("", content, privateKeyHex)
+}
 
-	file, err := os.Open(path)
+// Decrypt for a file inside fsys, such as one compiled in with //go:embed. A
+// Loader built WithFS finds the file and its keys there by itself.
+func DecryptFS(fsys fs.FS, name string, privateKeyHex ...string) ([]EnvVar, error) {
+	content, err := fs.ReadFile(fsys, name)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
+	return defaultLoader.decryptContent(name, content, privateKeyHex)
+}
 
-	var vars []EnvVar
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		varName, value, ok := splitEnvLine(scanner.Text(), "")
-		if !ok {
-			continue
-		}
-		if strings.HasPrefix(value, encryptedPrefix) {
-			value, err = decryptSecretStrict(privateKey, value[len(encryptedPrefix):])
-			if err != nil {
-				return nil, fmt.Errorf("%s: %s: %w", path, varName, err)
-			}
+func (l *Loader) decryptContent(path string, content []byte, privateKeyHex []string) ([]EnvVar, error) {
+	var keys []*ecies.PrivateKey
+	if len(privateKeyHex) > 0 {
+		var err error
+		if keys, err = parsePrivateKeys(strings.Join(privateKeyHex, ",")); err != nil {
+			return nil, err
 		}
-		vars = append(vars, EnvVar{varName, value})
 	}
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("%s: %w", path, err)
+	if l.securesMemory() {
+		l.lockKeys([]EnvFile{{path, keys}})
+	}
+	vars, failed, err := l.decodeVars(path, content, keys, l.lookupEnv(), nil)
+	if l.securesMemory() {
+		zeroKeys([]EnvFile{{path, keys}})
+	}
+	if err == nil {
+		err = firstFailure(failed)
+	}
+	if err != nil {
+		return nil, err
 	}
 	return vars, nil
 }
 
-func Getenv(key string) string {
-	envFile, err := getEnvFile()
-	if Debug && (envFile.Key == nil || err != nil) {
-		fmt.Printf("Error finding envFile (%+v): %+v\n", envFile, err)
+// DecryptFile with path taken relative to the Loader's directory.
+func (l *Loader) DecryptFile(path string, privateKeyHex string) ([]EnvVar, error) {
+	keys, err := parsePrivateKeys(privateKeyHex)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
 	}
+	return l.decryptFiles([]EnvFile{{l.path(path), keys}})
+}
+
+// Reads from a snapshot taken on first use; see Reload and ReloadOnChange.
+// "" both when the variable is not in the file and, unless Lenient, when any
+// value in the file failed to decrypt; LookupEnv and GetenvStrict tell which.
+// With SecureMemory a secret is a view of locked memory that Reload, Close and
+// a ReloadOnChange rebuild zero, even while another goroutine is reading it;
+// strings.Clone it to keep it past the snapshot, or to read it while another
+// goroutine may reload. LookupEnv and GetenvStrict return the same views.
+func Getenv(key string) string {
+	return defaultLoader.Getenv(key)
+}
+
+func (l *Loader) Getenv(key string) string {
+	value, _ := l.LookupEnv(key)
+	return value
+}
+
+// Like os.LookupEnv: ok is false for a variable the file does not set, and
+// also for one whose value did not decrypt, whether or not Lenient.
+func LookupEnv(key string) (string, bool) {
+	return defaultLoader.LookupEnv(key)
+}
+
+func (l *Loader) LookupEnv(key string) (string, bool) {
+	value, err := l.GetenvStrict(key)
 	if err != nil {
-		return ""
+		l.log().Debug("variable not available", "var", key, "error", err)
+		return "", false
+	}
+	return value, true
+}
+
+// Getenv with the reason it would return "": ErrNotFound, what finding the
+// file failed with, or a *DecryptError, for this variable or, unless Lenient,
+// any other in the file.
+func GetenvStrict(key string) (string, error) {
+	return defaultLoader.GetenvStrict(key)
+}
+
+func (l *Loader) GetenvStrict(key string) (value string, err error) {
+	l.readSnapshot(func(s *snapshot) { value, err = s.getenv(key) })
+	return value, err
+}
+
+func (s *snapshot) getenv(key string) (string, error) {
+	if s.err != nil {
+		return "", s.err
 	}
-	vars, err := getEnvVars(&envFile, key)
-	if Debug && (len(vars) != 1 || err != nil) {
-		fmt.Printf("Error retrieving (%s) (%d values): %+v\n", key, len(vars), err)
+	i, ok := s.last[key]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, key)
 	}
-	if err != nil || len(vars) == 0 {
-		return ""
+	if s.failed != nil && s.failed[i] != nil {
+		return "", s.failed[i]
 	}
-	return vars[0].Value
+	return s.vars[i].Value, nil
 }
 
+// Every variable in the file, or nothing when the file could not be found or,
+// unless Lenient, any value in it failed to decrypt.
 func Environ() []string {
-	envFile, err := getEnvFile()
-	if Debug && (envFile.Key == nil || err != nil) {
-		fmt.Printf("Error finding envFile (%+v): %+v\n", envFile, err)
-	}
+	return defaultLoader.Environ()
+}
+
+func (l *Loader) Environ() (env []string) {
+	l.readSnapshot(func(s *snapshot) {
+		if s.err != nil {
+			env = []string{}
+			return
+		}
+		env = environ(s.vars)
+	})
+	return env
+}
+
+// Environ for callers that must not start without their secrets: the same
+// discovery, read afresh rather than from the snapshot, and no usable key or a
+// value that does not decrypt is an error rather than an empty or short list.
+func EnvironStrict() ([]string, error) {
+	return defaultLoader.EnvironStrict()
+}
+
+func (l *Loader) EnvironStrict() ([]string, error) {
+	envFiles, err := l.getEnvFiles()
 	if err != nil {
-		return []string{}
-	}
-	vars, err := getEnvVars(&envFile, "")
-	if Debug && (len(vars) == 0 || err != nil) {
-		fmt.Printf("Error retrieving all values (%d found): %+v\n", len(vars), err)
+		return nil, err
 	}
+	vars, err := l.decryptFiles(envFiles)
 	if err != nil {
-		return []string{}
+		return nil, err
 	}
+	return environ(vars), nil
+}
+
+func environ(vars []EnvVar) []string {
 	env := make([]string, 0, len(vars))
 	for _, v := range vars {
 		env = append(env, v.Name+"="+v.Value)
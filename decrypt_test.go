@@ -1,7 +1,12 @@
 package dotenvx
 
 import (
+	"embed"
+	"errors"
+	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"testing"
@@ -14,10 +19,12 @@ func inTempDir(t *testing.T) string {
 	tempDir := t.TempDir()
 	originalDir, _ := os.Getwd()
 	os.Chdir(tempDir)
+	resetCache()
 	return originalDir
 }
 
 func clearEnvKeys() {
+	resetCache()
 	for _, env := range os.Environ() {
 		if strings.HasPrefix(env, "DOTENV_PRIVATE_KEY") {
 			parts := strings.SplitN(env, "=", 2)
@@ -33,12 +40,12 @@ func TestGetEnvFile_NoKeys(t *testing.T) {
 	defer os.Chdir(inTempDir(t))
 	clearEnvKeys()
 
-	_, err := getEnvFile()
+	_, err := defaultLoader.getEnvFile()
 	if err == nil {
 		t.Error("Expected error when no keys present")
 	}
-	if err.Error() != "No key found" {
-		t.Errorf("Expected 'No key found', got %q", err.Error())
+	if !errors.Is(err, ErrNoKey) || err.Error() != "No key found" {
+		t.Errorf("Expected ErrNoKey, got %v", err)
 	}
 }
 
@@ -51,14 +58,14 @@ func TestGetEnvFile_ValidKeyAndFile(t *testing.T) {
 	os.Setenv("DOTENV_PRIVATE_KEY", "2ff9d3716a37e630e0643447beac508a1e9963444d3ca00a6a22dbf2970dc03d")
 	defer os.Unsetenv("DOTENV_PRIVATE_KEY")
 
-	envFile, err := getEnvFile()
+	envFile, err := defaultLoader.getEnvFile()
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
 	if envFile.Path != ".env" {
 		t.Errorf("Expected path .env, got %q", envFile.Path)
 	}
-	if envFile.Key == nil {
+	if envFile.Keys == nil {
 		t.Error("Expected valid key, got nil")
 	}
 }
@@ -72,12 +79,12 @@ func TestGetEnvFile_InvalidKeyFormat(t *testing.T) {
 	os.Setenv("DOTENV_PRIVATE_KEY", "invalid-key")
 	defer os.Unsetenv("DOTENV_PRIVATE_KEY")
 
-	_, err := getEnvFile()
+	_, err := defaultLoader.getEnvFile()
 	if err == nil {
 		t.Error("Expected error with invalid key")
 	}
-	if err.Error() != "No valid file/key combination found" {
-		t.Errorf("Expected 'No valid file/key combination found', got %q", err.Error())
+	if !errors.Is(err, ErrInvalidKey) || !strings.Contains(err.Error(), "DOTENV_PRIVATE_KEY") {
+		t.Errorf("Expected ErrInvalidKey naming DOTENV_PRIVATE_KEY, got %v", err)
 	}
 }
 
@@ -89,9 +96,9 @@ func TestGetEnvFile_MissingFile(t *testing.T) {
 	os.Setenv("DOTENV_PRIVATE_KEY", "2ff9d3716a37e630e0643447beac508a1e9963444d3ca00a6a22dbf2970dc03d")
 	defer os.Unsetenv("DOTENV_PRIVATE_KEY")
 
-	_, err := getEnvFile()
-	if err == nil {
-		t.Error("Expected error when file missing")
+	_, err := defaultLoader.getEnvFile()
+	if !errors.Is(err, ErrNoMatchingFile) {
+		t.Errorf("Expected ErrNoMatchingFile when file missing, got %v", err)
 	}
 }
 
@@ -104,7 +111,7 @@ func TestGetEnvFile_CustomEnvironment(t *testing.T) {
 	os.Setenv("DOTENV_PRIVATE_KEY_STAGING", "2ff9d3716a37e630e0643447beac508a1e9963444d3ca00a6a22dbf2970dc03d")
 	defer os.Unsetenv("DOTENV_PRIVATE_KEY_STAGING")
 
-	envFile, err := getEnvFile()
+	envFile, err := defaultLoader.getEnvFile()
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -122,7 +129,7 @@ func TestGetEnvFile_UnderscoreToDot(t *testing.T) {
 	os.Setenv("DOTENV_PRIVATE_KEY_QA_TEST", "2ff9d3716a37e630e0643447beac508a1e9963444d3ca00a6a22dbf2970dc03d")
 	defer os.Unsetenv("DOTENV_PRIVATE_KEY_QA_TEST")
 
-	envFile, err := getEnvFile()
+	envFile, err := defaultLoader.getEnvFile()
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -138,13 +145,27 @@ func TestDecryptSecret_Valid(t *testing.T) {
 
 	encrypted := "BL8cvfR8496FAJV3dbdSZj/D6qlhOc3lAhuAB24AGp4WASPH8BBoe21T+T9jlO/M0GY03RZ94Etk7VPWIP21vh+YLGu0fWe2usFdTFs+/BnlsT8K8+V9Xte/yXA2NhrRxy3T7ygL"
 
-	result := decryptSecret(privateKey, encrypted)
+	result := decryptSecret([]*ecies.PrivateKey{privateKey}, encrypted)
 	if result != "hello" {
 		t.Errorf("Expected 'hello', got %q", result)
 	}
 }
 
-// Test parseEnvVar function
+// Test parsing a single line: the first variable in it named name (any, if
+// name is empty), decrypted leniently
+func parseEnvVar(line string, keys []*ecies.PrivateKey, name string) EnvVar {
+	for _, entry := range parseEnv(line) {
+		if name == "" || entry.name == name {
+			encrypted := strings.HasPrefix(entry.value, encryptedPrefix)
+			if encrypted {
+				entry.value = decryptSecret(keys, entry.value[len(encryptedPrefix):])
+			}
+			return EnvVar{entry.name, entry.value, encrypted}
+		}
+	}
+	return EnvVar{}
+}
+
 func TestParseEnvVar_PlainValue(t *testing.T) {
 	line := "TEST=plain value"
 	result := parseEnvVar(line, nil, "")
@@ -201,7 +222,7 @@ func TestParseEnvVar_EncryptedValue(t *testing.T) {
 	privateKey, _ := ecies.NewPrivateKeyFromHex(keyHex)
 
 	line := `TEST=encrypted:BL8cvfR8496FAJV3dbdSZj/D6qlhOc3lAhuAB24AGp4WASPH8BBoe21T+T9jlO/M0GY03RZ94Etk7VPWIP21vh+YLGu0fWe2usFdTFs+/BnlsT8K8+V9Xte/yXA2NhrRxy3T7ygL`
-	result := parseEnvVar(line, privateKey, "")
+	result := parseEnvVar(line, []*ecies.PrivateKey{privateKey}, "")
 
 	if result.Value != "hello" {
 		t.Errorf("Expected decrypted value 'hello', got %q", result.Value)
@@ -226,9 +247,9 @@ func TestParseEnvVar_SpecificName(t *testing.T) {
 
 // Test getEnvVars function
 func TestGetEnvVars_FileNotFound(t *testing.T) {
-	envFile := &EnvFile{Path: "nonexistent.env", Key: nil}
+	envFile := &EnvFile{Path: "nonexistent.env", Keys: nil}
 
-	vars, err := getEnvVars(envFile, "")
+	vars, err := defaultLoader.getEnvVars(envFile, "")
 	if err == nil {
 		t.Error("Expected error for nonexistent file")
 	}
@@ -247,8 +268,8 @@ VAR2=value2
 VAR3=value3`
 	os.WriteFile("test.env", []byte(content), 0644)
 
-	envFile := &EnvFile{Path: "test.env", Key: nil}
-	vars, err := getEnvVars(envFile, "")
+	envFile := &EnvFile{Path: "test.env", Keys: nil}
+	vars, err := defaultLoader.getEnvVars(envFile, "")
 
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
@@ -267,8 +288,8 @@ VAR2=value2
 VAR3=value3`
 	os.WriteFile("test.env", []byte(content), 0644)
 
-	envFile := &EnvFile{Path: "test.env", Key: nil}
-	vars, err := getEnvVars(envFile, "VAR2")
+	envFile := &EnvFile{Path: "test.env", Keys: nil}
+	vars, err := defaultLoader.getEnvVars(envFile, "VAR2")
 
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
@@ -291,26 +312,55 @@ func TestGetEnvFile_DebugMode(t *testing.T) {
 	defer func() { Debug = false }()
 
 	// Test with no keys
-	_, _ = getEnvFile()
+	_, _ = defaultLoader.getEnvFile()
 
 	// Test with invalid key
 	os.WriteFile(".env", []byte("TEST=value"), 0644)
 	os.Setenv("DOTENV_PRIVATE_KEY", "invalid")
 	defer os.Unsetenv("DOTENV_PRIVATE_KEY")
-	_, _ = getEnvFile()
+	_, _ = defaultLoader.getEnvFile()
 
 	// Test with missing file
 	os.Remove(".env")
 	os.Setenv("DOTENV_PRIVATE_KEY", "2ff9d3716a37e630e0643447beac508a1e9963444d3ca00a6a22dbf2970dc03d")
-	_, _ = getEnvFile()
+	_, _ = defaultLoader.getEnvFile()
+}
+
+// cmd/decrypt prints the environment on stdout, so Debug must not
+func TestDebug_WritesToStderr(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	clearEnvKeys()
+
+	Debug = true
+	defer func() { Debug = false }()
+
+	captured := map[**os.File]*os.File{}
+	for _, stream := range []**os.File{&os.Stdout, &os.Stderr} {
+		r, w, _ := os.Pipe()
+		original := *stream
+		*stream, captured[stream] = w, r
+		defer func() { *stream = original }()
+	}
+	Getenv("TEST")
+	os.Stdout.Close()
+	os.Stderr.Close()
+
+	stdout, _ := io.ReadAll(captured[&os.Stdout])
+	stderr, _ := io.ReadAll(captured[&os.Stderr])
+	if len(stdout) != 0 {
+		t.Errorf("Expected nothing on stdout, got %q", stdout)
+	}
+	if !strings.Contains(string(stderr), "level=DEBUG") || !strings.Contains(string(stderr), "No key found") {
+		t.Errorf("Expected debug events on stderr, got %q", stderr)
+	}
 }
 
 func TestGetEnvVars_DebugMode(t *testing.T) {
 	Debug = true
 	defer func() { Debug = false }()
 
-	envFile := &EnvFile{Path: "nonexistent.env", Key: nil}
-	_, _ = getEnvVars(envFile, "")
+	envFile := &EnvFile{Path: "nonexistent.env", Keys: nil}
+	_, _ = defaultLoader.getEnvVars(envFile, "")
 }
 
 func TestGetenv_DebugMode(t *testing.T) {
@@ -387,7 +437,7 @@ func TestGetEnvFile_UnsuffixedWinsInEitherOrder(t *testing.T) {
 	for _, order := range orders {
 		setKeys(t, order...)
 
-		envFile, err := getEnvFile()
+		envFile, err := defaultLoader.getEnvFile()
 		if err != nil {
 			t.Fatalf("For order %v: expected no error, got %v", order, err)
 		}
@@ -407,7 +457,7 @@ func TestGetEnvFile_SingleSuffixedKeyWithOtherKeysPresent(t *testing.T) {
 	os.WriteFile(".env.staging", []byte("TEST=staging"), 0644)
 	setKeys(t, "DOTENV_PRIVATE_KEY_STAGING", "DOTENV_PRIVATE_KEY", "DOTENV_PRIVATE_KEY_PROD")
 
-	envFile, err := getEnvFile()
+	envFile, err := defaultLoader.getEnvFile()
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -428,14 +478,14 @@ func TestGetEnvFile_MultipleSuffixedIsAmbiguous(t *testing.T) {
 	} {
 		setKeys(t, order...)
 
-		_, err := getEnvFile()
+		_, err := defaultLoader.getEnvFile()
 		if err == nil {
 			t.Fatalf("For order %v: expected an ambiguity error", order)
 		}
 		expected := "Ambiguous private key: DOTENV_PRIVATE_KEY_STAGING -> .env.staging, " +
 			"DOTENV_PRIVATE_KEY_WORKFLOWSTORE -> .env.workflowstore, and no DOTENV_PRIVATE_KEY for .env to break the tie"
-		if err.Error() != expected {
-			t.Errorf("For order %v: expected %q, got %q", order, expected, err.Error())
+		if !errors.Is(err, ErrAmbiguousKey) || err.Error() != expected {
+			t.Errorf("For order %v: expected ErrAmbiguousKey %q, got %q", order, expected, err.Error())
 		}
 		if got := Getenv("TEST"); got != "" {
 			t.Errorf("For order %v: expected Getenv to yield \"\", got %q", order, got)
@@ -446,6 +496,60 @@ func TestGetEnvFile_MultipleSuffixedIsAmbiguous(t *testing.T) {
 	}
 }
 
+// The header line dotenvx writes for keyHex, under varName.
+func publicKeyLine(varName, keyHex string) string {
+	privateKey, _ := ecies.NewPrivateKeyFromHex(keyHex)
+	return varName + "=" + privateKey.PublicKey.Hex(true) + "\n"
+}
+
+func TestGetEnvFile_PublicKeyBreaksTheTie(t *testing.T) {
+	t.Parallel()
+	dir := writeFiles(t, map[string]string{
+		".env.staging":    publicKeyLine("DOTENV_PUBLIC_KEY_STAGING", testKeyHex) + "TEST=staging\n",
+		".env.production": publicKeyLine("DOTENV_PUBLIC_KEY_PRODUCTION", prodKeyHex) + "TEST=" + prodCipher + "\n",
+	})
+	// A key copied under the wrong name: only the production key fits its file
+	l := New(WithDir(dir), WithEnviron(environOf(
+		"DOTENV_PRIVATE_KEY_STAGING="+prodKeyHex, "DOTENV_PRIVATE_KEY_PRODUCTION="+prodKeyHex)))
+
+	envFile, err := l.getEnvFile()
+	if err != nil || filepath.Base(envFile.Path) != ".env.production" {
+		t.Fatalf("Expected .env.production chosen by its public key, got %q %v", envFile.Path, err)
+	}
+	if got := l.Getenv("TEST"); got != "world" {
+		t.Errorf("Expected world, got %q", got)
+	}
+
+	both := New(WithDir(dir), WithEnviron(environOf(
+		"DOTENV_PRIVATE_KEY_STAGING="+testKeyHex, "DOTENV_PRIVATE_KEY_PRODUCTION="+prodKeyHex)))
+	if _, err := both.getEnvFile(); !errors.Is(err, ErrAmbiguousKey) {
+		t.Errorf("Expected two matching keys to stay ambiguous, got %v", err)
+	}
+}
+
+func TestPublicKeyMismatch(t *testing.T) {
+	t.Parallel()
+	dir := writeFiles(t, map[string]string{
+		".env.production": publicKeyLine("DOTENV_PUBLIC_KEY_PRODUCTION", prodKeyHex) + "TEST=" + prodCipher + "\n",
+		".env.plain":      publicKeyLine("DOTENV_PUBLIC_KEY_PLAIN", prodKeyHex) + "TEST=plain\n",
+	})
+
+	l := New(WithDir(dir), WithFile(".env.production"), WithKeys(testKeyHex))
+	_, err := l.GetenvStrict("TEST")
+	if !errors.Is(err, ErrKeyMismatch) || !strings.Contains(err.Error(), ".env.production: Key does not match") {
+		t.Errorf("Expected ErrKeyMismatch naming the file, got %v", err)
+	}
+
+	rotating := New(WithDir(dir), WithFile(".env.production"), WithKeys(testKeyHex+","+prodKeyHex))
+	if got := rotating.Getenv("TEST"); got != "world" {
+		t.Errorf("Expected one matching key of several to do, got %q", got)
+	}
+	plain := New(WithDir(dir), WithFile(".env.plain"), WithKeys(testKeyHex))
+	if got := plain.Getenv("TEST"); got != "plain" {
+		t.Errorf("Expected a file with nothing encrypted to need no matching key, got %q", got)
+	}
+}
+
 func TestGetEnvFile_AmbiguityDebugMode(t *testing.T) {
 	defer os.Chdir(inTempDir(t))
 
@@ -456,7 +560,7 @@ func TestGetEnvFile_AmbiguityDebugMode(t *testing.T) {
 	os.WriteFile(".env.staging", []byte("TEST=staging"), 0644)
 
 	setKeys(t, "DOTENV_PRIVATE_KEY_STAGING", "DOTENV_PRIVATE_KEY")
-	if envFile, err := getEnvFile(); err != nil || envFile.Path != ".env" {
+	if envFile, err := defaultLoader.getEnvFile(); err != nil || envFile.Path != ".env" {
 		t.Errorf("Expected .env with no error, got %q %v", envFile.Path, err)
 	}
 
@@ -464,7 +568,7 @@ func TestGetEnvFile_AmbiguityDebugMode(t *testing.T) {
 	os.Remove(".env")
 	os.WriteFile(".env.prod", []byte("TEST=prod"), 0644)
 	setKeys(t, "DOTENV_PRIVATE_KEY_STAGING", "DOTENV_PRIVATE_KEY_PROD", "DOTENV_PRIVATE_KEY")
-	if _, err := getEnvFile(); err == nil {
+	if _, err := defaultLoader.getEnvFile(); err == nil {
 		t.Error("Expected an ambiguity error")
 	}
 }
@@ -486,7 +590,7 @@ func TestGetEnvFile_MultipleKeysWarning(t *testing.T) {
 	defer os.Unsetenv("DOTENV_PRIVATE_KEY")
 	defer os.Unsetenv("DOTENV_PRIVATE_KEY_PROD")
 
-	envFile, err := getEnvFile()
+	envFile, err := defaultLoader.getEnvFile()
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -510,9 +614,9 @@ func TestGetEnvVars_PermissionDenied(t *testing.T) {
 
 	keyHex := "2ff9d3716a37e630e0643447beac508a1e9963444d3ca00a6a22dbf2970dc03d"
 	privateKey, _ := ecies.NewPrivateKeyFromHex(keyHex)
-	envFile := &EnvFile{Path: "test.env", Key: privateKey}
+	envFile := &EnvFile{Path: "test.env", Keys: []*ecies.PrivateKey{privateKey}}
 
-	vars, err := getEnvVars(envFile, "")
+	vars, err := defaultLoader.getEnvVars(envFile, "")
 	if err == nil {
 		t.Error("Expected error for permission denied")
 	}
@@ -552,7 +656,7 @@ func TestGetEnvFile_EmptyKeyValue(t *testing.T) {
 	os.Setenv("DOTENV_PRIVATE_KEY", "")
 	defer os.Unsetenv("DOTENV_PRIVATE_KEY")
 
-	_, err := getEnvFile()
+	_, err := defaultLoader.getEnvFile()
 	if err == nil {
 		t.Error("Expected error when key value is empty")
 	}
@@ -599,8 +703,12 @@ func TestDecryptFile_WrongKeyErrors(t *testing.T) {
 	if err == nil {
 		t.Fatalf("Expected error with a valid but wrong key, got vars %+v", vars)
 	}
-	if !strings.Contains(err.Error(), "SECRET") {
-		t.Errorf("Expected the error to name SECRET, got %v", err)
+	var decryptErr *DecryptError
+	if !errors.As(err, &decryptErr) || decryptErr.Path != ".env" || decryptErr.Line != 1 || decryptErr.Name != "SECRET" {
+		t.Errorf("Expected a DecryptError for .env:1 SECRET, got %#v", err)
+	}
+	if err.Error() != ".env:1: SECRET: "+decryptErr.Err.Error() {
+		t.Errorf("Expected the message to lead with .env:1: SECRET, got %v", err)
 	}
 }
 
@@ -609,8 +717,8 @@ func TestDecryptFile_InvalidKeyHex(t *testing.T) {
 
 	os.WriteFile(".env", []byte("PLAIN=value\n"), 0644)
 
-	if _, err := DecryptFile(".env", "not-hex"); err == nil {
-		t.Error("Expected error with invalid key hex")
+	if _, err := DecryptFile(".env", "not-hex"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected ErrInvalidKey with invalid key hex, got %v", err)
 	}
 }
 
@@ -631,8 +739,9 @@ func TestDecryptFile_BadBase64Errors(t *testing.T) {
 	if err == nil {
 		t.Fatal("Expected error for undecodable base64")
 	}
-	if !strings.Contains(err.Error(), "SECRET") {
-		t.Errorf("Expected the error to name SECRET, got %v", err)
+	var decryptErr *DecryptError
+	if !errors.As(err, &decryptErr) || decryptErr.Name != "SECRET" {
+		t.Errorf("Expected a DecryptError naming SECRET, got %v", err)
 	}
 }
 
@@ -696,3 +805,298 @@ ENCRYPTED=encrypted:BL8cvfR8496FAJV3dbdSZj/D6qlhOc3lAhuAB24AGp4WASPH8BBoe21T+T9j
 		t.Errorf("Expected 2 vars, got %d", len(vars))
 	}
 }
+
+func TestEnvironStrict_Decrypts(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	setKeys(t, "DOTENV_PRIVATE_KEY")
+
+	os.WriteFile(".env", []byte("PLAIN=one\nSECRET="+testCipher+"\n"), 0644)
+
+	env, err := EnvironStrict()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(env) != 2 || env[0] != "PLAIN=one" || env[1] != "SECRET=hello" {
+		t.Errorf("Expected [PLAIN=one SECRET=hello], got %v", env)
+	}
+}
+
+func TestEnvironStrict_NoKeyErrors(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	clearEnvKeys()
+
+	if env, err := EnvironStrict(); err == nil {
+		t.Errorf("Expected an error with no key, got %v", env)
+	}
+}
+
+// Environ would hand back a list without SECRET in it; strict refuses outright.
+func TestEnvironStrict_WrongKeyErrors(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	clearEnvKeys()
+
+	wrong, err := ecies.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	os.WriteFile(".env", []byte("SECRET="+testCipher+"\n"), 0644)
+	t.Setenv("DOTENV_PRIVATE_KEY", wrong.Hex())
+
+	_, err = EnvironStrict()
+	if err == nil || !strings.Contains(err.Error(), "SECRET") {
+		t.Errorf("Expected an error naming SECRET, got %v", err)
+	}
+}
+
+// Test .env.keys discovery
+func TestGetEnvFile_KeyFromKeysFile(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	clearEnvKeys()
+
+	os.WriteFile(".env", []byte("SECRET="+testCipher+"\n"), 0644)
+	os.WriteFile(".env.keys", []byte("# .env\nDOTENV_PRIVATE_KEY=\""+testKeyHex+"\"\n"), 0600)
+
+	envFile, err := defaultLoader.getEnvFile()
+	if err != nil || envFile.Path != ".env" {
+		t.Fatalf("Expected .env with no error, got %q %v", envFile.Path, err)
+	}
+	if got := Getenv("SECRET"); got != "hello" {
+		t.Errorf("Expected 'hello', got %q", got)
+	}
+}
+
+func TestKeyEntries_ProcessEnvWinsOverKeysFile(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	setKeys(t, "DOTENV_PRIVATE_KEY")
+
+	os.WriteFile(".env.keys", []byte("DOTENV_PRIVATE_KEY=from-file\nDOTENV_PRIVATE_KEY_CI=ci-key\nOTHER=ignored\n"), 0600)
+
+	entries := defaultLoader.keyEntries()
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %+v", entries)
+	}
+	if entries[0].varName != "DOTENV_PRIVATE_KEY" || entries[0].keyHex != testKeyHex || entries[0].source != "environment" {
+		t.Errorf("Expected DOTENV_PRIVATE_KEY from the environment, got %+v", entries[0])
+	}
+	if entries[1].varName != "DOTENV_PRIVATE_KEY_CI" || entries[1].keyHex != "ci-key" || entries[1].source != ".env.keys" {
+		t.Errorf("Expected DOTENV_PRIVATE_KEY_CI from .env.keys, got %+v", entries[1])
+	}
+}
+
+func TestKeyEntries_KeysPathOverride(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	clearEnvKeys()
+
+	os.WriteFile(".env.keys", []byte("DOTENV_PRIVATE_KEY=default-location\n"), 0600)
+	os.WriteFile("elsewhere.keys", []byte("DOTENV_PRIVATE_KEY_PRODUCTION=override\n"), 0600)
+	t.Setenv("DOTENV_KEYS_PATH", "elsewhere.keys")
+
+	entries := defaultLoader.keyEntries()
+	if len(entries) != 1 || entries[0].keyHex != "override" || entries[0].source != "elsewhere.keys" {
+		t.Errorf("Expected only the key from elsewhere.keys, got %+v", entries)
+	}
+}
+
+func TestKeyEntries_MissingKeysPathDebugMode(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	clearEnvKeys()
+
+	Debug = true
+	defer func() { Debug = false }()
+
+	t.Setenv("DOTENV_KEYS_PATH", "absent.keys")
+	if entries := defaultLoader.keyEntries(); len(entries) != 0 {
+		t.Errorf("Expected no entries, got %+v", entries)
+	}
+	if _, err := defaultLoader.getEnvFile(); !errors.Is(err, ErrNoKey) {
+		t.Errorf("Expected ErrNoKey, got %v", err)
+	}
+}
+
+// Test comma-separated keys, as during a rotation
+// "world" encrypted to prodKeyHex's public key, from .env.production
+const prodCipher = "encrypted:BJExC5swxOAqabtuaJVYpmwmDWyktO4yC0ONvceZPExR0timrv31PFrDytTk1MLX3KmpKR7kiHrbBMWQL5GbiS+yWPTEyxZyBlgu2QIKEOgMRQ3K6g4m2xRyAxlx/F8OqUDQDqQJ"
+
+func TestParsePrivateKeys(t *testing.T) {
+	keys, err := parsePrivateKeys(prodKeyHex + ", " + testKeyHex + ",")
+	if err != nil || len(keys) != 2 {
+		t.Fatalf("Expected 2 keys, got %d %v", len(keys), err)
+	}
+	if keys[0].Hex() != prodKeyHex || keys[1].Hex() != testKeyHex {
+		t.Error("Expected the keys in the order given")
+	}
+	for _, bad := range []string{"", " , ", testKeyHex + ",not-hex"} {
+		if _, err := parsePrivateKeys(bad); err == nil {
+			t.Errorf("For %q: expected an error", bad)
+		}
+	}
+}
+
+func TestGetenv_CommaSeparatedKeysDecryptEitherValue(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	clearEnvKeys()
+
+	os.WriteFile(".env", []byte("OLD="+testCipher+"\nNEW="+prodCipher+"\n"), 0644)
+	t.Setenv("DOTENV_PRIVATE_KEY", prodKeyHex+","+testKeyHex)
+
+	if got := Getenv("OLD"); got != "hello" {
+		t.Errorf("Expected OLD 'hello' from the second key, got %q", got)
+	}
+	if got := Getenv("NEW"); got != "world" {
+		t.Errorf("Expected NEW 'world' from the first key, got %q", got)
+	}
+}
+
+func TestDecryptFile_CommaSeparatedKeys(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+
+	os.WriteFile(".env", []byte("OLD="+testCipher+"\nNEW="+prodCipher+"\n"), 0644)
+
+	vars, err := DecryptFile(".env", testKeyHex+","+prodKeyHex)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if vars[0].Value != "hello" || vars[1].Value != "world" {
+		t.Errorf("Expected OLD=hello NEW=world, got %+v", vars)
+	}
+}
+
+func TestDecryptFile_NoKeyInListOpensValue(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+
+	wrong, _ := ecies.GenerateKey()
+	os.WriteFile(".env", []byte("OLD="+testCipher+"\nNEW="+prodCipher+"\n"), 0644)
+
+	_, err := DecryptFile(".env", prodKeyHex+","+wrong.Hex())
+	if err == nil {
+		t.Fatal("Expected an error for a value neither key opens")
+	}
+	if !strings.Contains(err.Error(), "OLD") || !strings.Contains(err.Error(), "none of 2 keys") {
+		t.Errorf("Expected the error to name OLD and both keys, got %v", err)
+	}
+}
+
+func TestLookupEnv_SetUnsetAndEmpty(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	setKeys(t, "DOTENV_PRIVATE_KEY")
+	os.WriteFile(".env", []byte("SECRET="+testCipher+"\nEMPTY=\n"), 0644)
+
+	if value, ok := LookupEnv("SECRET"); !ok || value != "hello" {
+		t.Errorf("Expected hello, true, got %q, %v", value, ok)
+	}
+	if value, ok := LookupEnv("EMPTY"); !ok || value != "" {
+		t.Errorf("Expected \"\", true for an empty value, got %q, %v", value, ok)
+	}
+	if _, ok := LookupEnv("ABSENT"); ok {
+		t.Error("Expected false for a variable the file does not set")
+	}
+}
+
+func TestGetenvStrict_Reasons(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	clearEnvKeys()
+
+	if _, err := GetenvStrict("SECRET"); !errors.Is(err, ErrNoKey) {
+		t.Errorf("Expected ErrNoKey, got %v", err)
+	}
+
+	setKeys(t, "DOTENV_PRIVATE_KEY")
+	os.WriteFile(".env", []byte("SECRET="+testCipher+"\n"), 0644)
+	Reload()
+	if value, err := GetenvStrict("SECRET"); err != nil || value != "hello" {
+		t.Errorf("Expected hello, got %q %v", value, err)
+	}
+	if _, err := GetenvStrict("ABSENT"); !errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), "ABSENT") {
+		t.Errorf("Expected ErrNotFound naming ABSENT, got %v", err)
+	}
+}
+
+// The production incident this guards against: a wrong key, and every
+// password quietly "".
+func TestGetenv_WrongKeyServesNothing(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	clearEnvKeys()
+	t.Setenv("DOTENV_PRIVATE_KEY", prodKeyHex)
+	os.WriteFile(".env", []byte("PLAIN=plain\nSECRET="+testCipher+"\n"), 0644)
+
+	var decryptErr *DecryptError
+	if _, err := GetenvStrict("PLAIN"); !errors.As(err, &decryptErr) || decryptErr.Name != "SECRET" {
+		t.Errorf("Expected the DecryptError for SECRET even when asking for PLAIN, got %v", err)
+	}
+	if _, ok := LookupEnv("PLAIN"); ok || Getenv("PLAIN") != "" {
+		t.Error("Expected PLAIN to be unavailable while SECRET does not decrypt")
+	}
+	if env := Environ(); len(env) != 0 {
+		t.Errorf("Expected Environ to yield nothing, got %v", env)
+	}
+}
+
+func TestGetenv_LenientServesTheRest(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	clearEnvKeys()
+	t.Setenv("DOTENV_PRIVATE_KEY", prodKeyHex)
+	os.WriteFile(".env", []byte("PLAIN=plain\nSECRET="+testCipher+"\n"), 0644)
+
+	Lenient = true
+	defer func() { Lenient = false }()
+
+	if got := Getenv("PLAIN"); got != "plain" {
+		t.Errorf("Expected plain, got %q", got)
+	}
+	if got := Environ(); len(got) != 2 || got[1] != "SECRET=" {
+		t.Errorf("Expected PLAIN and an empty SECRET, got %v", got)
+	}
+	var decryptErr *DecryptError
+	if _, err := GetenvStrict("SECRET"); !errors.As(err, &decryptErr) || decryptErr.Line != 2 {
+		t.Errorf("Expected a DecryptError for line 2, got %v", err)
+	}
+	if _, ok := LookupEnv("SECRET"); ok {
+		t.Error("Expected LookupEnv to report SECRET unset even when lenient")
+	}
+	if _, err := EnvironStrict(); !errors.As(err, &decryptErr) {
+		t.Errorf("Expected EnvironStrict to stay strict, got %v", err)
+	}
+}
+
+func TestDecrypt_Reader(t *testing.T) {
+	vars, err := Decrypt(strings.NewReader("PLAIN=plain\nSECRET="+testCipher+"\n"), prodKeyHex, testKeyHex)
+	if err != nil || len(vars) != 2 || vars[1].Value != "hello" {
+		t.Errorf("Expected SECRET=hello with either key, got %+v %v", vars, err)
+	}
+
+	var decryptErr *DecryptError
+	_, err = Decrypt(strings.NewReader("PLAIN=plain\nSECRET=" + testCipher + "\n"))
+	if !errors.As(err, &decryptErr) || !errors.Is(err, ErrNoKey) || decryptErr.Path != "" {
+		t.Errorf("Expected a path-less DecryptError wrapping ErrNoKey, got %v", err)
+	}
+	if err != nil && !strings.HasPrefix(err.Error(), "line 2: SECRET: ") {
+		t.Errorf("Expected the message to start with the line, got %q", err.Error())
+	}
+
+	if vars, err := Decrypt(strings.NewReader("PLAIN=plain\n")); err != nil || vars[0].Value != "plain" {
+		t.Errorf("Expected plain content to need no key, got %+v %v", vars, err)
+	}
+	if _, err := Decrypt(strings.NewReader("A=${B}\nB=${A}\n")); err == nil || strings.HasPrefix(err.Error(), ":") {
+		t.Errorf("Expected a cycle error without an empty path, got %v", err)
+	}
+	if _, err := Decrypt(strings.NewReader(""), "not-hex"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected ErrInvalidKey, got %v", err)
+	}
+}
+
+//go:embed .env .env.production
+var embedded embed.FS
+
+func TestDecryptFS_Embedded(t *testing.T) {
+	vars, err := DecryptFS(embedded, ".env.production", prodKeyHex)
+	if err != nil || vars[len(vars)-1] != (EnvVar{"GREETING", "world", true}) {
+		t.Errorf("Expected GREETING=world, got %+v %v", vars, err)
+	}
+
+	if _, err := DecryptFS(embedded, ".env", prodKeyHex); !errors.Is(err, ErrKeyMismatch) || !strings.HasPrefix(err.Error(), ".env: ") {
+		t.Errorf("Expected ErrKeyMismatch for .env, got %v", err)
+	}
+	if _, err := DecryptFS(embedded, ".env.absent", prodKeyHex); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected fs.ErrNotExist, got %v", err)
+	}
+}
@@ -0,0 +1,203 @@
+package dotenvx
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type NodeKind int
+
+const (
+	BlankNode NodeKind = iota
+	CommentNode
+	AssignmentNode
+	// A line that is none of the above, which dotenv skips and so do we
+	IgnoredNode
+)
+
+// One piece of a Document. Text is the node as written, through the newline
+// that ends it, so a Document's nodes laid end to end are the file; Line is
+// where it started when parsed. For an assignment Value is what the quotes
+// hold, unescaped but not expanded or decrypted, and Quote is ', " or `, or 0
+// for a bare value.
+type Node struct {
+	Kind   NodeKind
+	Text   string
+	Line   int
+	Name   string
+	Value  string
+	Quote  byte
+	Export bool
+
+	// Offsets into Text, for edits that touch nothing else
+	nameStart, valueStart, valueEnd int
+}
+
+// An env file as a sequence of nodes, for changing some variables while every
+// other byte -- header block, comments, export prefixes, quoting, blank lines
+// -- stays as it was. Bytes returns the input unchanged until an edit.
+type Document struct {
+	nodes []Node
+}
+
+func ParseDocument(content []byte) *Document {
+	src := string(content)
+	p := envParser{src: src, line: 1}
+	d := &Document{}
+	for p.pos < len(src) {
+		start, line := p.pos, p.line
+		entry, ok := p.assignment()
+		if !ok {
+			p.pos = start
+		}
+		end := min(p.lineEnd(p.pos)+1, len(src))
+		node := Node{Text: src[start:end], Line: line}
+		if ok {
+			node.Kind, node.Name, node.Value, node.Quote = AssignmentNode, entry.name, entry.value, entry.quote
+			node.nameStart, node.valueStart, node.valueEnd = entry.nameStart-start, entry.valueStart-start, entry.valueEnd-start
+			node.Export = strings.TrimLeft(node.Text[:node.nameStart], " \t\r\f\v") != ""
+		} else {
+			switch trimmed := strings.TrimSpace(node.Text); {
+			case trimmed == "":
+				node.Kind = BlankNode
+			case trimmed[0] == '#':
+				node.Kind = CommentNode
+			default:
+				node.Kind = IgnoredNode
+			}
+		}
+		d.nodes = append(d.nodes, node)
+		p.advance(end)
+	}
+	return d
+}
+
+func (d *Document) Bytes() []byte {
+	var out []byte
+	for _, node := range d.nodes {
+		out = append(out, node.Text...)
+	}
+	return out
+}
+
+func (d *Document) Nodes() []Node {
+	return slices.Clone(d.nodes)
+}
+
+// The value of the last assignment to name, the one dotenv uses.
+func (d *Document) Get(name string) (string, bool) {
+	for i := len(d.nodes) - 1; i >= 0; i-- {
+		if d.nodes[i].Kind == AssignmentNode && d.nodes[i].Name == name {
+			return d.nodes[i].Value, true
+		}
+	}
+	return "", false
+}
+
+// Replaces the value of every assignment to name, in the quoting each already
+// has where the value can be written in it, or appends NAME="value" if there is
+// none. The value is stored as given: Set(name, Get(name)) changes nothing.
+func (d *Document) Set(name, value string) error {
+	if !validName(name) {
+		return fmt.Errorf("%q is not a variable name", name)
+	}
+	found := false
+	for i := range d.nodes {
+		if d.nodes[i].Kind == AssignmentNode && d.nodes[i].Name == name {
+			d.setValue(i, value)
+			found = true
+		}
+	}
+	if found {
+		return nil
+	}
+	if n := len(d.nodes); n > 0 && !strings.HasSuffix(d.nodes[n-1].Text, "\n") {
+		d.nodes[n-1].Text += "\n"
+	}
+	line := 1
+	for _, node := range d.nodes {
+		line += strings.Count(node.Text, "\n")
+	}
+	written := quoteValue(value, '"')
+	d.nodes = append(d.nodes, Node{
+		Kind: AssignmentNode, Text: name + "=" + written + "\n", Line: line,
+		Name: name, Value: value, Quote: '"',
+		nameStart: 0, valueStart: len(name) + 1, valueEnd: len(name) + 1 + len(written),
+	})
+	return nil
+}
+
+func (d *Document) setValue(i int, value string) {
+	node := &d.nodes[i]
+	if node.Value == value {
+		return
+	}
+	quote := node.Quote
+	if !canQuote(value, quote) {
+		quote = '"'
+	}
+	written := quoteValue(value, quote)
+	node.Text = node.Text[:node.valueStart] + written + node.Text[node.valueEnd:]
+	node.valueEnd = node.valueStart + len(written)
+	node.Value, node.Quote = value, quote
+}
+
+// Removes every assignment to name, with nothing else around it; reports
+// whether there was one.
+func (d *Document) Delete(name string) bool {
+	n := len(d.nodes)
+	d.nodes = slices.DeleteFunc(d.nodes, func(node Node) bool { return node.Kind == AssignmentNode && node.Name == name })
+	return len(d.nodes) != n
+}
+
+// Renames every assignment to from, leaving its value as written.
+func (d *Document) Rename(from, to string) error {
+	if !validName(to) {
+		return fmt.Errorf("%q is not a variable name", to)
+	}
+	found := false
+	for i := range d.nodes {
+		node := &d.nodes[i]
+		if node.Kind != AssignmentNode || node.Name != from {
+			continue
+		}
+		node.Text = node.Text[:node.nameStart] + to + node.Text[node.nameStart+len(from):]
+		shift := len(to) - len(from)
+		node.valueStart, node.valueEnd, node.Name = node.valueStart+shift, node.valueEnd+shift, to
+		found = true
+	}
+	if !found {
+		return fmt.Errorf("%w: %s", ErrNotFound, from)
+	}
+	return nil
+}
+
+func validName(name string) bool {
+	return name != "" && strings.IndexFunc(name, func(r rune) bool { return r > 0x7f || !isKeyByte(byte(r)) }) < 0
+}
+
+// Whether value reads back unchanged from the quoting: single quotes and
+// backticks cannot hold their own quote, and a bare value ends at a newline or
+// #, loses surrounding blanks, and must not look quoted.
+func canQuote(value string, quote byte) bool {
+	switch quote {
+	case '"':
+		return true
+	case '\'', '`':
+		return !strings.ContainsRune(value, rune(quote)) && !strings.Contains(value, "\r")
+	}
+	return !strings.ContainsAny(value, "#\n\r") && strings.TrimSpace(value) == value &&
+		(value == "" || !strings.ContainsRune("'\"`", rune(value[0])))
+}
+
+// The inverse of unquote.
+func quoteValue(value string, quote byte) string {
+	switch quote {
+	case 0:
+		return value
+	case '"':
+		value = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`).Replace(value)
+	}
+	return string(quote) + value + string(quote)
+}
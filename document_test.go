@@ -0,0 +1,197 @@
+package dotenvx
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func golden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("%s differs from the golden file:\n%s", name, got)
+	}
+}
+
+func readDocument(t *testing.T, name string) []byte {
+	t.Helper()
+	if content, err := embedded.ReadFile(name); err == nil {
+		return content
+	}
+	content, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+var nodeKinds = []string{"blank", "comment", "assignment", "ignored"}
+
+func dumpNodes(d *Document) []byte {
+	var out []byte
+	for _, node := range d.Nodes() {
+		out = fmt.Appendf(out, "%d %s %q", node.Line, nodeKinds[node.Kind], node.Text)
+		if node.Kind == AssignmentNode {
+			out = fmt.Appendf(out, " name=%s value=%q quote=%q export=%t", node.Name, node.Value, node.Quote, node.Export)
+		}
+		out = append(out, '\n')
+	}
+	return out
+}
+
+func TestDocument_RoundTrip(t *testing.T) {
+	for _, name := range []string{".env", ".env.production", "testdata/document.env"} {
+		content := readDocument(t, name)
+		d := ParseDocument(content)
+		if got := d.Bytes(); string(got) != string(content) {
+			t.Errorf("%s: expected the bytes back unchanged, got:\n%s", name, got)
+		}
+		golden(t, strings.TrimPrefix(filepath.Base(name), ".")+".nodes", dumpNodes(d))
+	}
+	for _, tt := range parseConformance {
+		if got := ParseDocument([]byte(tt.src)).Bytes(); string(got) != tt.src {
+			t.Errorf("%s: expected %q back, got %q", tt.name, tt.src, got)
+		}
+	}
+}
+
+func TestDocument_AgreesWithParser(t *testing.T) {
+	content := readDocument(t, "testdata/document.env")
+	entries := parseEnv(string(content))
+	var nodes []Node
+	for _, node := range ParseDocument(content).Nodes() {
+		if node.Kind == AssignmentNode {
+			nodes = append(nodes, node)
+		}
+	}
+	if len(nodes) != len(entries) {
+		t.Fatalf("Expected %d assignments, got %d", len(entries), len(nodes))
+	}
+	for i, entry := range entries {
+		if node := nodes[i]; node.Name != entry.name || node.Value != entry.value || node.Quote != entry.quote || node.Line != entry.line {
+			t.Errorf("Expected %+v, got %+v", entry, node)
+		}
+	}
+}
+
+func TestDocument_Edits(t *testing.T) {
+	d := ParseDocument(readDocument(t, "testdata/document.env"))
+	for name, value := range map[string]string{
+		"SPACED":   "still bare",
+		"BARE":     "now #hashed",
+		"SINGLE":   "it's",
+		"BACKTICK": "tick",
+		"MULTI":    "one\ntwo",
+		"DUP":      "both",
+		"ADDED":    `a "new" one`,
+	} {
+		if err := d.Set(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !d.Delete("YAML") || d.Delete("YAML") {
+		t.Error("Expected Delete to report whether it removed anything")
+	}
+	if err := d.Rename("EMPTY", "NOW_FILLED"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Set("NOW_FILLED", "x"); err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "document.edited.env", d.Bytes())
+
+	again := ParseDocument(d.Bytes())
+	for name, want := range map[string]string{"BARE": "now #hashed", "SINGLE": "it's", "MULTI": "one\ntwo", "DUP": "both", "ADDED": `a "new" one`, "NOW_FILLED": "x"} {
+		if got, ok := again.Get(name); !ok || got != want {
+			t.Errorf("Expected %s=%q to read back, got %q", name, want, got)
+		}
+	}
+	if _, ok := again.Get("EMPTY"); ok {
+		t.Error("Expected EMPTY renamed away")
+	}
+}
+
+func TestDocument_Get(t *testing.T) {
+	d := ParseDocument([]byte("A=first\nA=second\n"))
+	if got, ok := d.Get("A"); !ok || got != "second" {
+		t.Errorf("Expected the last assignment to win, got %q", got)
+	}
+	if _, ok := d.Get("B"); ok {
+		t.Error("Expected B not found")
+	}
+}
+
+func TestDocument_SetValuesReadBack(t *testing.T) {
+	for _, value := range []string{"", "plain", " padded ", `back\slash\`, `"quoted"`, "'single'", "`tick`", "a#b", "multi\nline", "cr\r\nlf", `$HOME`, `\n literal`} {
+		for _, src := range []string{"V=x\n", "V='x'\n", "V=\"x\"\n", "V=`x`\n", ""} {
+			d := ParseDocument([]byte(src))
+			if err := d.Set("V", value); err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := ParseDocument(d.Bytes()).Get("V"); got != value {
+				t.Errorf("%q set into %q: expected %q back, got %q from %q", value, src, value, got, d.Bytes())
+			}
+		}
+	}
+}
+
+func TestDocument_SetKeepsUnchanged(t *testing.T) {
+	content := readDocument(t, "testdata/document.env")
+	d := ParseDocument(content)
+	for _, node := range d.Nodes() {
+		if node.Kind == AssignmentNode {
+			value, _ := d.Get(node.Name)
+			d.Set(node.Name, value)
+		}
+	}
+	// DUP's first assignment takes the last one's value; nothing else moves
+	want := strings.Replace(string(content), "DUP=first", "DUP=second", 1)
+	if got := string(d.Bytes()); got != want {
+		t.Errorf("Expected Set(name, Get(name)) to change nothing else, got:\n%s", got)
+	}
+}
+
+func TestDocument_SetAppendsAfterMissingNewline(t *testing.T) {
+	d := ParseDocument([]byte("A=1"))
+	d.Set("B", "2")
+	if got := string(d.Bytes()); got != "A=1\nB=\"2\"\n" {
+		t.Errorf("Expected B appended on its own line, got %q", got)
+	}
+	if nodes := d.Nodes(); nodes[1].Line != 2 {
+		t.Errorf("Expected B on line 2, got %d", nodes[1].Line)
+	}
+}
+
+func TestDocument_InvalidNames(t *testing.T) {
+	d := ParseDocument([]byte("A=1\n"))
+	for _, name := range []string{"", "has space", "A=B", "é"} {
+		if err := d.Set(name, "x"); err == nil {
+			t.Errorf("Expected Set(%q) rejected", name)
+		}
+		if err := d.Rename("A", name); err == nil {
+			t.Errorf("Expected Rename to %q rejected", name)
+		}
+	}
+	if err := d.Rename("MISSING", "B"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if got := string(d.Bytes()); got != "A=1\n" {
+		t.Errorf("Expected nothing changed, got %q", got)
+	}
+}
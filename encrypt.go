@@ -0,0 +1,114 @@
+package dotenvx
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	ecies "github.com/ecies/go/v2"
+)
+
+const publicKeyVar = "DOTENV_PUBLIC_KEY"
+
+// The inverse of decryptSecretStrict. ecies.Encrypt already lays the bytes out
+// the way eciesjs reads them -- uncompressed ephemeral key, 16-byte nonce, tag,
+// ciphertext -- so the result drops straight into a file dotenvx can read. An
+// empty plaintext is refused: its ciphertext is one neither side decrypts.
+func EncryptValue(publicKeyHex, plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", errors.New("An empty value cannot be encrypted")
+	}
+	publicKey, err := ecies.NewPublicKeyFromHex(publicKeyHex)
+	if err != nil {
+		return "", fmt.Errorf("public key: %w", err)
+	}
+	cipherBytes, err := ecies.Encrypt(publicKey, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(cipherBytes), nil
+}
+
+func isPublicKeyVar(varName string) bool {
+	return varName == publicKeyVar || strings.HasPrefix(varName, publicKeyVar+"_")
+}
+
+// The header dotenvx writes at the top of every encrypted file, whatever its
+// suffix, so a file only ever encrypts to its own key.
+func filePublicKey(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if publicKeyHex, ok := publicKeyOf(parseEnv(string(content))); ok {
+		return publicKeyHex, nil
+	}
+	return "", fmt.Errorf("%s: no %s header", path, publicKeyVar)
+}
+
+func publicKeyOf(entries []envEntry) (string, bool) {
+	for _, entry := range entries {
+		if isPublicKeyVar(entry.name) {
+			return entry.value, true
+		}
+	}
+	return "", false
+}
+
+// Any of keys will do, since during a rotation the variable holds the old key
+// beside the new one. A header that does not parse cannot rule a key out.
+func keysMatch(keys []*ecies.PrivateKey, publicKeyHex string) bool {
+	publicKey, err := ecies.NewPublicKeyFromHex(publicKeyHex)
+	if err != nil {
+		return true
+	}
+	return slices.ContainsFunc(keys, func(key *ecies.PrivateKey) bool { return key.PublicKey.Equals(publicKey) })
+}
+
+// Encrypts value to the file's own public key and rewrites only the value
+// assigned to name, or appends an assignment if there is none. Every other byte
+// of the file, export prefixes and comments included, is left as it was. The
+// value is a literal, not a template: decrypted values are expanded, so each $
+// is stored as \$ to come back as it went in, unless the assignment read back
+// is single-quoted and so not expanded. An empty value is stored as it is, as
+// EncryptInPlace leaves one, since it would not decrypt.
+func SetEncrypted(path, name, value string) error {
+	publicKeyHex, err := filePublicKey(path)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	doc := ParseDocument(content)
+	// Set keeps each assignment's quotes, and the last one is what is read
+	literal := strings.ReplaceAll(value, "$", `\$`)
+	nodes := doc.Nodes()
+	for i := len(nodes) - 1; i >= 0; i-- {
+		if nodes[i].Kind == AssignmentNode && nodes[i].Name == name {
+			if nodes[i].Quote == '\'' {
+				literal = value
+			}
+			break
+		}
+	}
+	encrypted := value
+	if value != "" {
+		if encrypted, err = EncryptValue(publicKeyHex, literal); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if err := doc.Set(name, encrypted); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, doc.Bytes(), info.Mode().Perm())
+}
@@ -0,0 +1,226 @@
+package dotenvx
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	ecies "github.com/ecies/go/v2"
+)
+
+const prodKeyHex = "7d797417f477635f8753c5325d5a68552ab7048f46c518be7f0ae3bc245d3ab8"
+
+// The repo's own .env and .env.production were written by the dotenvx CLI, so
+// their headers and keys are the compatibility fixtures.
+var fixtures = []struct{ path, keyHex string }{
+	{".env", testKeyHex},
+	{".env.production", prodKeyHex},
+}
+
+func TestEncryptValue_RoundTripsWithFixtures(t *testing.T) {
+	for _, fixture := range fixtures {
+		publicKeyHex, err := filePublicKey(fixture.path)
+		if err != nil {
+			t.Fatalf("%s: %v", fixture.path, err)
+		}
+		privateKey, _ := ecies.NewPrivateKeyFromHex(fixture.keyHex)
+		if derived := privateKey.PublicKey.Hex(true); derived != publicKeyHex {
+			t.Fatalf("%s: fixture key derives %s, header says %s", fixture.path, derived, publicKeyHex)
+		}
+
+		encrypted, err := EncryptValue(publicKeyHex, "round trip ✓")
+		if err != nil {
+			t.Fatalf("%s: %v", fixture.path, err)
+		}
+		if !strings.HasPrefix(encrypted, encryptedPrefix) {
+			t.Errorf("%s: expected an encrypted: prefix, got %q", fixture.path, encrypted)
+		}
+		plain, err := decryptSecretStrict([]*ecies.PrivateKey{privateKey}, encrypted[len(encryptedPrefix):])
+		if err != nil || plain != "round trip ✓" {
+			t.Errorf("%s: expected to decrypt our own ciphertext, got %q %v", fixture.path, plain, err)
+		}
+	}
+}
+
+func TestEncryptValue_OtherKeyCannotDecrypt(t *testing.T) {
+	publicKeyHex, _ := filePublicKey(".env")
+	encrypted, _ := EncryptValue(publicKeyHex, "secret")
+
+	prodKey, _ := ecies.NewPrivateKeyFromHex(prodKeyHex)
+	if _, err := decryptSecretStrict([]*ecies.PrivateKey{prodKey}, encrypted[len(encryptedPrefix):]); err == nil {
+		t.Error("Expected the production key to fail on a value encrypted to .env")
+	}
+}
+
+func TestEncryptValue_InvalidPublicKey(t *testing.T) {
+	if _, err := EncryptValue("not-hex", "secret"); err == nil {
+		t.Error("Expected error with invalid public key hex")
+	}
+}
+
+func TestEncryptValue_RefusesEmpty(t *testing.T) {
+	publicKeyHex, _ := filePublicKey(".env")
+	if _, err := EncryptValue(publicKeyHex, ""); err == nil {
+		t.Error("Expected an error for an empty value")
+	}
+}
+
+func TestFilePublicKey_NoHeader(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+
+	os.WriteFile(".env", []byte("PLAIN=value\n"), 0644)
+	if _, err := filePublicKey(".env"); err == nil {
+		t.Error("Expected error for a file without a public key")
+	}
+	if _, err := filePublicKey(".env.absent"); err == nil {
+		t.Error("Expected error for a missing file")
+	}
+}
+
+func copyFixture(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	copied := filepath.Join(t.TempDir(), filepath.Base(path))
+	os.WriteFile(copied, content, 0640)
+	return copied
+}
+
+func TestSetEncrypted_ReplacesAndAppends(t *testing.T) {
+	for _, fixture := range fixtures {
+		path := copyFixture(t, fixture.path)
+		original, _ := os.ReadFile(path)
+
+		if err := SetEncrypted(path, "GREETING", "replaced"); err != nil {
+			t.Fatalf("%s: %v", fixture.path, err)
+		}
+		if err := SetEncrypted(path, "ADDED", "appended"); err != nil {
+			t.Fatalf("%s: %v", fixture.path, err)
+		}
+
+		vars, err := DecryptFile(path, fixture.keyHex)
+		if err != nil {
+			t.Fatalf("%s: %v", fixture.path, err)
+		}
+		got := map[string]string{}
+		for _, v := range vars {
+			got[v.Name] = v.Value
+		}
+		if got["GREETING"] != "replaced" || got["ADDED"] != "appended" {
+			t.Errorf("%s: expected GREETING=replaced ADDED=appended, got %v", fixture.path, got)
+		}
+
+		// Only the GREETING line changed, and the new one went on the end
+		rewritten, _ := os.ReadFile(path)
+		originalLines := strings.Split(string(original), "\n")
+		rewrittenLines := strings.Split(string(rewritten), "\n")
+		for i, line := range originalLines {
+			if strings.Contains(line, "GREETING=") {
+				if strings.HasPrefix(line, "export ") != strings.HasPrefix(rewrittenLines[i], "export ") {
+					t.Errorf("%s: expected the export prefix kept, got %q", fixture.path, rewrittenLines[i])
+				}
+				continue
+			}
+			if line != "" && rewrittenLines[i] != line {
+				t.Errorf("%s: line %d changed from %q to %q", fixture.path, i+1, line, rewrittenLines[i])
+			}
+		}
+		if info, _ := os.Stat(path); info.Mode().Perm() != 0640 {
+			t.Errorf("%s: expected mode 0640 kept, got %v", fixture.path, info.Mode().Perm())
+		}
+	}
+}
+
+func TestSetEncrypted_NoHeader(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+
+	os.WriteFile(".env", []byte("PLAIN=value\n"), 0644)
+	if err := SetEncrypted(".env", "SECRET", "value"); err == nil {
+		t.Error("Expected error for a file without a public key")
+	}
+}
+
+func TestSetEncrypted_BadHeader(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+
+	os.WriteFile(".env", []byte(`DOTENV_PUBLIC_KEY="zz"`+"\n"), 0644)
+	if err := SetEncrypted(".env", "SECRET", "value"); err == nil {
+		t.Error("Expected error for an unparseable public key")
+	}
+}
+
+func TestSetEncrypted_ReplacesMultilineValueKeepingComment(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+
+	header := `DOTENV_PUBLIC_KEY="020c5f23e6e02f087af380212814755c22f3d742b218666642d1dec184b7c6ae69"` + "\n"
+	os.WriteFile(".env", []byte(header+"export CERT=\"-----BEGIN-----\nabc\n-----END-----\" # pem\nAFTER=kept\n"), 0644)
+
+	if err := SetEncrypted(".env", "CERT", "short"); err != nil {
+		t.Fatal(err)
+	}
+	content, _ := os.ReadFile(".env")
+	lines := strings.Split(string(content), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[1], `export CERT="encrypted:`) || !strings.HasSuffix(lines[1], `" # pem`) || lines[2] != "AFTER=kept" {
+		t.Errorf("Expected only the value replaced, got:\n%s", content)
+	}
+	vars, err := DecryptFile(".env", testKeyHex)
+	if err != nil || vars[1].Value != "short" || vars[2].Value != "kept" {
+		t.Errorf("Expected CERT=short AFTER=kept, got %+v %v", vars, err)
+	}
+}
+
+// A crash part way through must leave the old file or the new, so the new one
+// is renamed over the old rather than written into it.
+func TestSetEncrypted_ReplacesByRename(t *testing.T) {
+	path := copyFixture(t, ".env")
+	before, _ := os.Stat(path)
+
+	if err := SetEncrypted(path, "ADDED", "value"); err != nil {
+		t.Fatal(err)
+	}
+	after, _ := os.Stat(path)
+	if os.SameFile(before, after) {
+		t.Error("Expected the file replaced by a rename")
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("Expected no temporary file left, got %v", entries)
+	}
+}
+
+func TestSetEncrypted_DollarRoundTrips(t *testing.T) {
+	path := copyFixture(t, ".env")
+	content, _ := os.ReadFile(path)
+	os.WriteFile(path, append(content, "PRICE='old'\n"...), 0644)
+	for _, name := range []string{"DB_PASS", "PRICE"} {
+		for _, value := range []string{`s3cr$t "x"`, `${HOME}`, `a\$b`, `trailing$`, `$$`} {
+			if err := SetEncrypted(path, name, value); err != nil {
+				t.Fatal(err)
+			}
+			vars, err := DecryptFile(path, testKeyHex)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if i := slices.IndexFunc(vars, func(v EnvVar) bool { return v.Name == name }); vars[i].Value != value {
+				t.Errorf("%s: expected %q back, got %q", name, value, vars[i].Value)
+			}
+		}
+	}
+	if rewritten, _ := os.ReadFile(path); !strings.Contains(string(rewritten), "\nPRICE='encrypted:") {
+		t.Errorf("Expected PRICE kept single-quoted, got:\n%s", rewritten)
+	}
+}
+
+func TestSetEncrypted_EmptyStaysPlain(t *testing.T) {
+	path := copyFixture(t, ".env")
+	if err := SetEncrypted(path, "EMPTY", ""); err != nil {
+		t.Fatal(err)
+	}
+	vars, err := DecryptFile(path, testKeyHex)
+	if err != nil || vars[len(vars)-1] != (EnvVar{Name: "EMPTY"}) {
+		t.Errorf("Expected an empty EMPTY beside the rest of the file, got %+v %v", vars, err)
+	}
+}
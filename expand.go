@@ -0,0 +1,182 @@
+package dotenvx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Resolves $VAR, ${VAR}, ${VAR:-default}, ${VAR-default}, ${VAR:+alternate} and
+// ${VAR+alternate} in every entry not written in single quotes, the way dotenvx
+// does once values are decrypted. A reference is looked up in the file above
+// it, then in the environment lookupEnv reads, then further down the file, so
+// PATH=${PATH}:/app still reaches the process's PATH while a reference to a
+// later line works too -- which is also the only way to build a cycle, and a
+// cycle is an error rather than an empty string. \$ is a literal dollar.
+func expandEntries(entries []envEntry, lookupEnv func(string) (string, bool)) error {
+	x := expander{
+		entries:   entries,
+		lookupEnv: lookupEnv,
+		done:      make([]bool, len(entries)),
+		active:    make([]bool, len(entries)),
+	}
+	for i := range entries {
+		if err := x.resolve(i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type expander struct {
+	entries   []envEntry
+	lookupEnv func(string) (string, bool)
+	done      []bool
+	active    []bool
+	chain     []string
+}
+
+func (x *expander) resolve(i int) error {
+	entry := &x.entries[i]
+	if x.done[i] || entry.quote == '\'' {
+		return nil
+	}
+	if x.active[i] {
+		start := len(x.chain) - 1
+		for start > 0 && x.chain[start] != entry.name {
+			start--
+		}
+		return fmt.Errorf("reference cycle: %s -> %s", strings.Join(x.chain[start:], " -> "), entry.name)
+	}
+
+	x.active[i] = true
+	x.chain = append(x.chain, entry.name)
+	value, err := x.expand(entry.value, i)
+	x.chain = x.chain[:len(x.chain)-1]
+	x.active[i] = false
+	if err != nil {
+		return err
+	}
+	entry.value, x.done[i] = value, true
+	return nil
+}
+
+// A reference to an encrypted value makes the referring one a secret too.
+func (x *expander) entryValue(j, at int) (string, bool, error) {
+	err := x.resolve(j)
+	if x.entries[j].encrypted {
+		x.entries[at].encrypted = true
+	}
+	return x.entries[j].value, true, err
+}
+
+func (x *expander) lookup(name string, at int) (string, bool, error) {
+	for j := at - 1; j >= 0; j-- {
+		if x.entries[j].name == name {
+			return x.entryValue(j, at)
+		}
+	}
+	if value, ok := x.lookupEnv(name); ok {
+		return value, true, nil
+	}
+	// The last assignment is the one that counts, as everywhere in dotenv
+	for j := len(x.entries) - 1; j > at; j-- {
+		if x.entries[j].name == name {
+			return x.entryValue(j, at)
+		}
+	}
+	return "", false, nil
+}
+
+func (x *expander) expand(value string, at int) (string, error) {
+	if !strings.Contains(value, "$") {
+		return value, nil
+	}
+	var out strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch {
+		case value[i] == '\\' && i+1 < len(value) && value[i+1] == '$':
+			out.WriteByte('$')
+			i++
+		case value[i] != '$' || i+1 == len(value):
+			out.WriteByte(value[i])
+		case value[i+1] == '{':
+			end := closingBrace(value, i+2)
+			if end < 0 {
+				out.WriteString(value[i:])
+				return out.String(), nil
+			}
+			expanded, err := x.braced(value[i+2:end], at)
+			if err != nil {
+				return "", err
+			}
+			out.WriteString(expanded)
+			i = end
+		default:
+			end := i + 1
+			for end < len(value) && isNameByte(value[end], end == i+1) {
+				end++
+			}
+			if end == i+1 {
+				out.WriteByte('$')
+				continue
+			}
+			resolved, _, err := x.lookup(value[i+1:end], at)
+			if err != nil {
+				return "", err
+			}
+			out.WriteString(resolved)
+			i = end - 1
+		}
+	}
+	return out.String(), nil
+}
+
+// The body of ${...}: a name, optionally followed by one of the operators
+func (x *expander) braced(body string, at int) (string, error) {
+	nameEnd := 0
+	for nameEnd < len(body) && isNameByte(body[nameEnd], nameEnd == 0) {
+		nameEnd++
+	}
+	name, operator := body[:nameEnd], body[nameEnd:]
+	resolved, set, err := x.lookup(name, at)
+	if err != nil {
+		return "", err
+	}
+
+	if operator == "" {
+		return resolved, nil
+	}
+	// With the colon an empty value counts as unset, as in the shell
+	present, op, word := set, operator[0], operator[1:]
+	if op == ':' && len(word) > 0 {
+		present, op, word = resolved != "", word[0], word[1:]
+	}
+	switch {
+	case op == '-' && present:
+		return resolved, nil
+	case op == '+' && !present:
+		return "", nil
+	case op == '-' || op == '+':
+		return x.expand(word, at)
+	}
+	return "${" + body + "}", nil
+}
+
+func closingBrace(value string, from int) int {
+	depth := 1
+	for i := from; i < len(value); i++ {
+		switch value[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isNameByte(c byte, first bool) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || (!first && '0' <= c && c <= '9')
+}
@@ -0,0 +1,138 @@
+package dotenvx
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func expanded(t *testing.T, src string) map[string]string {
+	t.Helper()
+	entries := parseEnv(src)
+	if err := expandEntries(entries, os.LookupEnv); err != nil {
+		t.Fatalf("Expected no error expanding %q, got %v", src, err)
+	}
+	values := map[string]string{}
+	for _, entry := range entries {
+		values[entry.name] = entry.value
+	}
+	return values
+}
+
+func TestExpandEntries_Forms(t *testing.T) {
+	t.Setenv("FROM_PROCESS", "process")
+	t.Setenv("EMPTY_IN_PROCESS", "")
+	os.Unsetenv("NOT_SET_ANYWHERE")
+
+	tests := []struct{ src, want string }{
+		{"USER=admin\nV=${USER}", "admin"},
+		{"USER=admin\nV=$USER", "admin"},
+		{"USER=admin\nV=$USER.x", "admin.x"},
+		{"USER=admin\nV=\"postgres://${USER}:$USER@db\"", "postgres://admin:admin@db"},
+		{"V=${FROM_PROCESS}", "process"},
+		{"V=${NOT_SET_ANYWHERE}", ""},
+		{"V=${NOT_SET_ANYWHERE:-fallback}", "fallback"},
+		{"V=${NOT_SET_ANYWHERE-fallback}", "fallback"},
+		{"V=${EMPTY_IN_PROCESS:-fallback}", "fallback"},
+		{"V=${EMPTY_IN_PROCESS-fallback}", ""},
+		{"V=${FROM_PROCESS:-fallback}", "process"},
+		{"V=${FROM_PROCESS:+alternate}", "alternate"},
+		{"V=${FROM_PROCESS+alternate}", "alternate"},
+		{"V=${EMPTY_IN_PROCESS:+alternate}", ""},
+		{"V=${EMPTY_IN_PROCESS+alternate}", "alternate"},
+		{"V=${NOT_SET_ANYWHERE+alternate}", ""},
+		{"HOST=db\nV=${NOT_SET_ANYWHERE:-${HOST}:5432}", "db:5432"},
+		{"V=${NOT_SET_ANYWHERE:-{braces}}", "{braces}"},
+		{`V="\$FROM_PROCESS"`, "$FROM_PROCESS"},
+		{"V=cost $5 and $", "cost $5 and $"},
+		{"V=${unterminated", "${unterminated"},
+		{"V=${FROM_PROCESS:?unsupported}", "${FROM_PROCESS:?unsupported}"},
+		{"V='${FROM_PROCESS}'", "${FROM_PROCESS}"},
+		{"V=`${FROM_PROCESS}`", "process"},
+	}
+	for _, tc := range tests {
+		if got := expanded(t, tc.src)["V"]; got != tc.want {
+			t.Errorf("For %q: expected %q, got %q", tc.src, tc.want, got)
+		}
+	}
+}
+
+func TestExpandEntries_LookupOrder(t *testing.T) {
+	t.Setenv("SHADOWED", "process")
+
+	values := expanded(t, "SHADOWED=file\nV=${SHADOWED}")
+	if values["V"] != "file" {
+		t.Errorf("Expected a variable defined above to win over the process, got %q", values["V"])
+	}
+
+	values = expanded(t, "V=${SHADOWED}\nSHADOWED=file")
+	if values["V"] != "process" {
+		t.Errorf("Expected the process to win over a variable defined below, got %q", values["V"])
+	}
+
+	os.Unsetenv("LATER")
+	values = expanded(t, "V=${LATER}\nLATER=first\nLATER=${OTHER}last\nOTHER=other-")
+	if values["V"] != "other-last" {
+		t.Errorf("Expected the last definition below, itself expanded, got %q", values["V"])
+	}
+}
+
+func TestExpandEntries_SelfReferenceReachesProcess(t *testing.T) {
+	t.Setenv("PATH_LIKE", "/usr/bin")
+
+	values := expanded(t, "PATH_LIKE=${PATH_LIKE}:/app\nPATH_LIKE=${PATH_LIKE}:/more")
+	if values["PATH_LIKE"] != "/usr/bin:/app:/more" {
+		t.Errorf("Expected /usr/bin:/app:/more, got %q", values["PATH_LIKE"])
+	}
+}
+
+func TestExpandEntries_Cycles(t *testing.T) {
+	os.Unsetenv("A")
+	os.Unsetenv("B")
+	os.Unsetenv("C")
+
+	for src, chain := range map[string]string{
+		"A=${B}\nB=${A}":         "A -> B -> A",
+		"A=${B}\nB=${C}\nC=x$A":  "A -> B -> C -> A",
+		"A=${B:-${C}}\nC=${A}\n": "A -> C -> A",
+	} {
+		err := expandEntries(parseEnv(src), os.LookupEnv)
+		if err == nil || !strings.Contains(err.Error(), chain) {
+			t.Errorf("For %q: expected a cycle error naming %s, got %v", src, chain, err)
+		}
+	}
+}
+
+func TestGetenv_Expands(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	setKeys(t, "DOTENV_PRIVATE_KEY")
+	os.Unsetenv("DB_USER")
+
+	os.WriteFile(".env", []byte("DB_USER=admin\nDB_PASS="+testCipher+"\nDATABASE_URL=postgres://${DB_USER}:${DB_PASS}@db\n"), 0644)
+
+	if got := Getenv("DATABASE_URL"); got != "postgres://admin:hello@db" {
+		t.Errorf("Expected the decrypted password expanded in, got %q", got)
+	}
+	vars, err := DecryptFile(".env", testKeyHex)
+	if err != nil || vars[2].Value != "postgres://admin:hello@db" {
+		t.Errorf("Expected DecryptFile to expand too, got %+v %v", vars, err)
+	}
+}
+
+func TestGetenv_CycleYieldsNothing(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	setKeys(t, "DOTENV_PRIVATE_KEY")
+	os.Unsetenv("A")
+	os.Unsetenv("B")
+
+	Debug = true
+	defer func() { Debug = false }()
+
+	os.WriteFile(".env", []byte("A=${B}\nB=${A}\n"), 0644)
+	if got := Getenv("A"); got != "" {
+		t.Errorf("Expected \"\" for a cycle, got %q", got)
+	}
+	if _, err := DecryptFile(".env", testKeyHex); err == nil || !strings.Contains(err.Error(), ".env") {
+		t.Errorf("Expected a cycle error naming .env, got %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+package dotenvx
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	ecies "github.com/ecies/go/v2"
+)
+
+// The files a framework reads for an environment, most specific first, in the
+// order dotenvx --convention loads them so the first to set a variable wins.
+func ConventionFiles(convention, environment string) ([]string, error) {
+	switch convention {
+	case "nextjs":
+		files := []string{".env." + environment + ".local"}
+		// Next.js leaves .env.local out of tests so they run the same everywhere
+		if environment != "test" {
+			files = append(files, ".env.local")
+		}
+		return append(files, ".env."+environment, ".env"), nil
+	}
+	return nil, fmt.Errorf("Unknown convention %q", convention)
+}
+
+// Without WithFile or WithFiles this is the single-file discovery it has always
+// been. Named files, one or several, each take the key named after them, and
+// may have none: .env.local is often plain.
+func (l *Loader) getEnvFiles() ([]EnvFile, error) {
+	if len(l.files) == 0 {
+		envFile, err := l.getEnvFile()
+		if err != nil {
+			return nil, err
+		}
+		l.unsetKeyVars()
+		return []EnvFile{envFile}, nil
+	}
+
+	var shared []*ecies.PrivateKey
+	keyHexes := map[string]string{}
+	if len(l.keys) > 0 {
+		keys, err := parsePrivateKeys(strings.Join(l.keys, ","))
+		if err != nil {
+			return nil, err
+		}
+		shared = keys
+	} else {
+		for _, entry := range l.keyEntries() {
+			keyHexes[entry.varName] = entry.keyHex
+		}
+	}
+
+	var envFiles []EnvFile
+	for _, name := range l.files {
+		path := l.path(name)
+		if _, err := l.stat(path); err != nil {
+			l.log().Debug("file missing", "file", path)
+			continue
+		}
+		envFile := EnvFile{path, shared}
+		if varName := keyVarForEnvFile(filepath.Base(path)); keyHexes[varName] != "" {
+			keys, err := parsePrivateKeys(keyHexes[varName])
+			if err != nil {
+				l.log().Warn("key invalid", "key_var", varName, "error", err)
+				return nil, fmt.Errorf("%s: %w", varName, err)
+			}
+			l.log().Debug("key found", "key_var", varName, "file", path)
+			envFile.Keys = keys
+		}
+		envFiles = append(envFiles, envFile)
+	}
+	if len(envFiles) == 0 {
+		return nil, fmt.Errorf("%w: none of %s exists", ErrNoMatchingFile, strings.Join(l.files, ", "))
+	}
+	l.unsetKeyVars()
+	return envFiles, nil
+}
+
+// Merges like dotenvx run -f a -f b: the first file to set a variable wins, or
+// with WithOverload the last; within a file the last assignment wins, as ever.
+// Each file expands against the process environment and the files before it,
+// in the same precedence, since that is what dotenvx has injected by then.
+func (l *Loader) readFiles(envFiles []EnvFile, a *arena) (vars []EnvVar, failed []error, err error) {
+	lookupEnv := l.lookupEnv()
+	if len(envFiles) == 1 {
+		return l.readVars(envFiles[0].Path, envFiles[0].Keys, lookupEnv, a)
+	}
+
+	index := map[string]int{}
+	var origin []int
+	layered := func(name string) (string, bool) {
+		i, merged := index[name]
+		if merged && l.overload {
+			return vars[i].Value, true
+		}
+		if value, ok := lookupEnv(name); ok {
+			return value, true
+		}
+		if merged {
+			return vars[i].Value, true
+		}
+		return "", false
+	}
+	for n, envFile := range envFiles {
+		fileVars, fileFailed, err := l.readVars(envFile.Path, envFile.Keys, layered, a)
+		if err != nil {
+			return nil, nil, err
+		}
+		for i, v := range fileVars {
+			var varErr error
+			if fileFailed != nil {
+				varErr = fileFailed[i]
+			}
+			j, seen := index[v.Name]
+			switch {
+			case !seen:
+				index[v.Name] = len(vars)
+				vars, failed, origin = append(vars, v), append(failed, varErr), append(origin, n)
+			case origin[j] == n || l.overload:
+				vars[j], failed[j], origin[j] = v, varErr, n
+			}
+		}
+	}
+	if firstFailure(failed) == nil {
+		failed = nil
+	}
+	return vars, failed, nil
+}
+
+func (l *Loader) decryptFiles(envFiles []EnvFile) ([]EnvVar, error) {
+	if l.securesMemory() {
+		l.lockKeys(envFiles)
+	}
+	vars, failed, err := l.readFiles(envFiles, nil)
+	if l.securesMemory() {
+		zeroKeys(envFiles)
+	}
+	if err == nil {
+		err = firstFailure(failed)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+// For errors that are about the files as a whole rather than one value in them.
+func pathOf(envFiles []EnvFile, err error) string {
+	var decryptErr *DecryptError
+	if errors.As(err, &decryptErr) {
+		return decryptErr.Path
+	}
+	paths := make([]string, 0, len(envFiles))
+	for _, envFile := range envFiles {
+		paths = append(paths, envFile.Path)
+	}
+	return strings.Join(paths, ", ")
+}
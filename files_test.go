@@ -0,0 +1,150 @@
+package dotenvx
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestConventionFiles_Nextjs(t *testing.T) {
+	files, err := ConventionFiles("nextjs", "production")
+	if want := []string{".env.production.local", ".env.local", ".env.production", ".env"}; err != nil || !slices.Equal(files, want) {
+		t.Errorf("Expected %v, got %v %v", want, files, err)
+	}
+	files, _ = ConventionFiles("nextjs", "test")
+	if want := []string{".env.test.local", ".env.test", ".env"}; !slices.Equal(files, want) {
+		t.Errorf("Expected .env.local left out of test, got %v", files)
+	}
+	if _, err := ConventionFiles("rails", "production"); err == nil {
+		t.Error("Expected an error for an unknown convention")
+	}
+}
+
+func TestWithFiles_FirstWinsEachWithItsKey(t *testing.T) {
+	t.Parallel()
+	dir := writeFiles(t, map[string]string{
+		".env.local":      "ONLY_LOCAL=local\nSHARED=local\n",
+		".env.production": "SHARED=" + prodCipher + "\nPROD=" + prodCipher + "\n",
+		".env":            "SHARED=" + testCipher + "\nBASE=" + testCipher + "\nBASE=last\n",
+	})
+	l := New(WithDir(dir), WithFiles(".env.local", ".env.production", ".env.absent", ".env"), WithEnviron(environOf(
+		"DOTENV_PRIVATE_KEY="+testKeyHex, "DOTENV_PRIVATE_KEY_PRODUCTION="+prodKeyHex)))
+
+	env, err := l.EnvironStrict()
+	if want := []string{"ONLY_LOCAL=local", "SHARED=local", "PROD=world", "BASE=last"}; err != nil || !slices.Equal(env, want) {
+		t.Errorf("Expected %v, got %v %v", want, env, err)
+	}
+	if got := l.Getenv("PROD"); got != "world" {
+		t.Errorf("Expected PROD decrypted with its own key, got %q", got)
+	}
+}
+
+func TestWithFiles_Overload(t *testing.T) {
+	t.Parallel()
+	dir := writeFiles(t, map[string]string{
+		".env":       "SHARED=" + testCipher + "\nBASE=base\n",
+		".env.local": "SHARED=local\n",
+	})
+	l := New(WithDir(dir), WithFiles(".env", ".env.local"), WithOverload(), WithKeys(testKeyHex))
+
+	env, err := l.EnvironStrict()
+	if want := []string{"SHARED=local", "BASE=base"}; err != nil || !slices.Equal(env, want) {
+		t.Errorf("Expected %v, got %v %v", want, env, err)
+	}
+}
+
+func TestWithFiles_ExpandsAcrossFiles(t *testing.T) {
+	t.Parallel()
+	dir := writeFiles(t, map[string]string{
+		".env.local": "HOST=localhost\n",
+		".env":       "HOST=db\nURL=postgres://${HOST}/app\n",
+	})
+	firstWins := New(WithDir(dir), WithFiles(".env.local", ".env"), WithEnviron(environOf()))
+	if got := firstWins.Getenv("URL"); got != "postgres://db/app" {
+		t.Errorf("Expected the file's own HOST above the reference, got %q", got)
+	}
+
+	dir = writeFiles(t, map[string]string{
+		".env.local": "HOST=localhost\n",
+		".env":       "URL=postgres://${HOST}/app\n",
+	})
+	layered := New(WithDir(dir), WithFiles(".env.local", ".env"), WithEnviron(environOf()))
+	if got := layered.Getenv("URL"); got != "postgres://localhost/app" {
+		t.Errorf("Expected HOST from the file before, got %q", got)
+	}
+	shadowed := New(WithDir(dir), WithFiles(".env.local", ".env"), WithEnviron(environOf("HOST=process")))
+	if got := shadowed.Getenv("URL"); got != "postgres://process/app" {
+		t.Errorf("Expected the process to win without overload, got %q", got)
+	}
+	overloaded := New(WithDir(dir), WithFiles(".env.local", ".env"), WithOverload(), WithEnviron(environOf("HOST=process")))
+	if got := overloaded.Getenv("URL"); got != "postgres://localhost/app" {
+		t.Errorf("Expected the earlier file to win with overload, got %q", got)
+	}
+}
+
+func TestWithFiles_MissingKeyFailsOnlyEncryptedFiles(t *testing.T) {
+	t.Parallel()
+	dir := writeFiles(t, map[string]string{
+		".env.local": "PLAIN=local\n",
+		".env":       "SECRET=" + testCipher + "\n",
+	})
+	l := New(WithDir(dir), WithFiles(".env.local", ".env"), WithEnviron(environOf()))
+
+	var decryptErr *DecryptError
+	_, err := l.EnvironStrict()
+	if !errors.As(err, &decryptErr) || decryptErr.Name != "SECRET" || !errors.Is(err, ErrNoKey) {
+		t.Errorf("Expected a DecryptError for SECRET wrapping ErrNoKey, got %v", err)
+	}
+
+	plainOnly := New(WithDir(dir), WithFiles(".env.local", ".env.absent"), WithEnviron(environOf()))
+	if got := plainOnly.Getenv("PLAIN"); got != "local" {
+		t.Errorf("Expected a plain file to need no key, got %q", got)
+	}
+}
+
+func TestWithFiles_NoneExist(t *testing.T) {
+	t.Parallel()
+	l := New(WithDir(t.TempDir()), WithFiles(".env.a", ".env.b"))
+	if err := l.Reload(); !errors.Is(err, ErrNoMatchingFile) || !strings.Contains(err.Error(), ".env.a, .env.b") {
+		t.Errorf("Expected ErrNoMatchingFile naming both files, got %v", err)
+	}
+}
+
+func TestWithFiles_InvalidKey(t *testing.T) {
+	t.Parallel()
+	dir := writeFiles(t, map[string]string{".env": "A=a\n", ".env.local": "B=b\n"})
+	l := New(WithDir(dir), WithFiles(".env.local", ".env"), WithEnviron(environOf("DOTENV_PRIVATE_KEY=nothex")))
+	if err := l.Reload(); !errors.Is(err, ErrInvalidKey) || !strings.Contains(err.Error(), "DOTENV_PRIVATE_KEY") {
+		t.Errorf("Expected ErrInvalidKey naming DOTENV_PRIVATE_KEY, got %v", err)
+	}
+}
+
+func TestWithFiles_ReloadOnChangeWatchesEveryFile(t *testing.T) {
+	t.Parallel()
+	dir := writeFiles(t, map[string]string{".env.local": "A=one\n", ".env": "B=one\n"})
+	l := New(WithDir(dir), WithFiles(".env.local", ".env"), WithReloadOnChange())
+	if got := l.Getenv("B"); got != "one" {
+		t.Fatalf("Expected one, got %q", got)
+	}
+	os.WriteFile(filepath.Join(dir, ".env"), []byte("B=two\n"), 0644)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(filepath.Join(dir, ".env"), later, later)
+	if got := l.Getenv("B"); got != "two" {
+		t.Errorf("Expected two after the second file changed, got %q", got)
+	}
+}
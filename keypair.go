@@ -0,0 +1,135 @@
+package dotenvx
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	ecies "github.com/ecies/go/v2"
+)
+
+const keysFile = ".env.keys"
+
+// The same blocks the dotenvx CLI writes, so files bootstrapped here and there
+// are indistinguishable.
+const publicKeyHeader = `#/-------------------[DOTENV_PUBLIC_KEY]--------------------/
+#/            public-key encryption for .env files          /
+#/       [how it works](https://dotenvx.com/encryption)     /
+#/----------------------------------------------------------/
+`
+
+const keysHeader = `#/------------------!DOTENV_PRIVATE_KEYS!-------------------/
+#/ private decryption keys. DO NOT commit to source control /
+#/     [how it works](https://dotenvx.com/encryption)       /
+#/----------------------------------------------------------/
+`
+
+// The inverse of envFileForKeyVar: .env.qa.test -> DOTENV_PRIVATE_KEY_QA_TEST
+func keyVarForEnvFile(fileName string) string {
+	if fileName == ".env" {
+		return keyVar
+	}
+	if suffix := strings.TrimPrefix(fileName, ".env."); suffix != fileName && suffix != "" {
+		return keyVar + "_" + strings.ToUpper(strings.ReplaceAll(suffix, ".", "_"))
+	}
+	return ""
+}
+
+// Creates a secp256k1 keypair for the env file at path: the public key goes in a
+// header at the top of that file (created if need be) and the private key is
+// appended to the .env.keys beside it, which is kept at 0600. Refuses to replace
+// a key either file already has, since that would orphan every value encrypted
+// to it.
+func GenerateKeyPair(path string) (publicKeyHex string, err error) {
+	privateVar := keyVarForEnvFile(filepath.Base(path))
+	if privateVar == "" {
+		return "", fmt.Errorf("%s: not a .env or .env.* file", path)
+	}
+	publicVar := publicKeyVar + strings.TrimPrefix(privateVar, keyVar)
+	keysPath := filepath.Join(filepath.Dir(path), keysFile)
+
+	content, mode, err := readIfExists(path, 0644)
+	if err != nil {
+		return "", err
+	}
+	if _, err := filePublicKey(path); err == nil {
+		return "", fmt.Errorf("%s already has a %s header", path, publicKeyVar)
+	}
+	keys, _, err := readIfExists(keysPath, 0600)
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range parseEnv(string(keys)) {
+		if entry.name == privateVar {
+			return "", fmt.Errorf("%s already has %s", keysPath, privateVar)
+		}
+	}
+
+	privateKey, err := ecies.GenerateKey()
+	if err != nil {
+		return "", err
+	}
+	// D.Bytes drops leading zeros, which would leave one key in 256 too short
+	privateKeyHex := hex.EncodeToString(privateKey.D.FillBytes(make([]byte, 32)))
+	publicKeyHex = privateKey.PublicKey.Hex(true)
+
+	// The private key first: a public key with nothing to decrypt it is worse
+	// than no keypair at all
+	if len(keys) == 0 {
+		keys = []byte(keysHeader)
+	} else if !strings.HasSuffix(string(keys), "\n") {
+		keys = append(keys, '\n')
+	}
+	keys = fmt.Appendf(keys, "\n# %s\n%s=%s\n", filepath.Base(path), privateVar, privateKeyHex)
+	if err := writeFileAtomic(keysPath, keys, 0600); err != nil {
+		return "", err
+	}
+
+	header := fmt.Sprintf("%s%s=%q\n\n# %s\n", publicKeyHeader, publicVar, publicKeyHex, filepath.Base(path))
+	if err := writeFileAtomic(path, append([]byte(header), content...), mode); err != nil {
+		return "", err
+	}
+	return publicKeyHex, nil
+}
+
+func readIfExists(path string, defaultMode os.FileMode) ([]byte, os.FileMode, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, defaultMode, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	content, err := os.ReadFile(path)
+	return content, info.Mode().Perm(), err
+}
+
+// A temporary file beside path, synced and renamed over it, so a reader sees
+// the old content or the new and never half of either.
+func writeFileAtomic(path string, content []byte, mode os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,128 @@
+package dotenvx
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestKeyVarForEnvFile_InvertsEnvFileForKeyVar(t *testing.T) {
+	for _, varName := range []string{"DOTENV_PRIVATE_KEY", "DOTENV_PRIVATE_KEY_STAGING", "DOTENV_PRIVATE_KEY_QA_TEST"} {
+		if got := keyVarForEnvFile(envFileForKeyVar(varName)); got != varName {
+			t.Errorf("For %q: expected the round trip to give it back, got %q", varName, got)
+		}
+	}
+	for _, fileName := range []string{"env", ".env.", ".envrc", "production.env"} {
+		if got := keyVarForEnvFile(fileName); got != "" {
+			t.Errorf("For %q: expected no key var, got %q", fileName, got)
+		}
+	}
+}
+
+func keysIn(t *testing.T, path string) map[string]string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := map[string]string{}
+	for _, entry := range parseEnv(string(content)) {
+		keys[entry.name] = entry.value
+	}
+	return keys
+}
+
+func TestGenerateKeyPair_NewFile(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+
+	publicKeyHex, err := GenerateKeyPair(".env.qa.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, _ := os.ReadFile(".env.qa.test")
+	if !strings.HasPrefix(string(content), publicKeyHeader+`DOTENV_PUBLIC_KEY_QA_TEST="`+publicKeyHex+`"`) {
+		t.Errorf("Expected the dotenvx header block, got:\n%s", content)
+	}
+	if info, _ := os.Stat(keysFile); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected %s at 0600, got %v", keysFile, info.Mode().Perm())
+	}
+	privateKeyHex := keysIn(t, keysFile)["DOTENV_PRIVATE_KEY_QA_TEST"]
+	if len(privateKeyHex) != 64 {
+		t.Fatalf("Expected a 64 hex digit private key, got %q", privateKeyHex)
+	}
+
+	if err := SetEncrypted(".env.qa.test", "SECRET", "generated"); err != nil {
+		t.Fatal(err)
+	}
+	vars, err := DecryptFile(".env.qa.test", privateKeyHex)
+	if err != nil || vars[len(vars)-1].Value != "generated" {
+		t.Errorf("Expected the new keypair to round trip, got %+v %v", vars, err)
+	}
+}
+
+func TestGenerateKeyPair_KeepsExistingContent(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+
+	os.WriteFile(".env.staging", []byte("PLAIN=value\n"), 0640)
+	os.WriteFile(keysFile, []byte("DOTENV_PRIVATE_KEY="+testKeyHex), 0644)
+	envBefore, _ := os.Stat(".env.staging")
+	keysBefore, _ := os.Stat(keysFile)
+
+	if _, err := GenerateKeyPair(".env.staging"); err != nil {
+		t.Fatal(err)
+	}
+	// Renamed over rather than rewritten, so a crash leaves the old file whole
+	envAfter, _ := os.Stat(".env.staging")
+	keysAfter, _ := os.Stat(keysFile)
+	if os.SameFile(envBefore, envAfter) || os.SameFile(keysBefore, keysAfter) {
+		t.Error("Expected both files replaced by a rename")
+	}
+
+	content, _ := os.ReadFile(".env.staging")
+	if !strings.HasSuffix(string(content), "\n# .env.staging\nPLAIN=value\n") {
+		t.Errorf("Expected the original content after the header, got:\n%s", content)
+	}
+	if info, _ := os.Stat(".env.staging"); info.Mode().Perm() != 0640 {
+		t.Errorf("Expected mode 0640 kept, got %v", info.Mode().Perm())
+	}
+	keys := keysIn(t, keysFile)
+	if keys["DOTENV_PRIVATE_KEY"] != testKeyHex || keys["DOTENV_PRIVATE_KEY_STAGING"] == "" {
+		t.Errorf("Expected the existing key kept and the new one appended, got %v", keys)
+	}
+	if info, _ := os.Stat(keysFile); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected %s tightened to 0600, got %v", keysFile, info.Mode().Perm())
+	}
+}
+
+func TestGenerateKeyPair_RefusesToReplaceKeys(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+
+	if _, err := GenerateKeyPair(".env"); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := os.ReadFile(keysFile)
+	if _, err := GenerateKeyPair(".env"); err == nil {
+		t.Error("Expected error for a file that already has a public key")
+	}
+
+	os.WriteFile(".env.staging", []byte("PLAIN=value\n"), 0644)
+	os.WriteFile(keysFile, append(before, "DOTENV_PRIVATE_KEY_STAGING=abc\n"...), 0600)
+	if _, err := GenerateKeyPair(".env.staging"); err == nil {
+		t.Error("Expected error for a key already in .env.keys")
+	}
+	if content, _ := os.ReadFile(".env.staging"); string(content) != "PLAIN=value\n" {
+		t.Errorf("Expected .env.staging untouched, got:\n%s", content)
+	}
+}
+
+func TestGenerateKeyPair_NotAnEnvFile(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+
+	if _, err := GenerateKeyPair("config.txt"); err == nil {
+		t.Error("Expected error for a file with no matching key var")
+	}
+	if _, err := os.Stat(keysFile); err == nil {
+		t.Errorf("Expected no %s written", keysFile)
+	}
+}
@@ -0,0 +1,86 @@
+package dotenvx
+
+import (
+	"fmt"
+	"os"
+)
+
+// Returned by Load and Overload. Name is set only when os.Setenv itself refused
+// a variable; otherwise nothing was set, and Err says why no file, key or value
+// could be used.
+type LoadError struct {
+	Path string
+	Name string
+	Err  error
+}
+
+func (e *LoadError) Error() string {
+	if e.Name != "" {
+		return fmt.Sprintf("%s: setenv %s: %v", e.Path, e.Name, e.Err)
+	}
+	return "dotenvx: load: " + e.Err.Error()
+}
+
+func (e *LoadError) Unwrap() error { return e.Err }
+
+// For libraries that call os.Getenv themselves: decrypts the file Environ
+// would and sets each variable not already in the process environment, like
+// dotenvx run. Returns the names it set. All-or-nothing on decryption, so a
+// wrong key never leaves half the secrets loaded.
+func Load() ([]string, error) {
+	return defaultLoader.Load()
+}
+
+// Load, but the file's values replace ones already set, like dotenvx run
+// --overload.
+func Overload() ([]string, error) {
+	return defaultLoader.Overload()
+}
+
+// Whatever the Loader reads from, these write to the process environment.
+func (l *Loader) Load() ([]string, error) {
+	return l.load(false)
+}
+
+func (l *Loader) Overload() ([]string, error) {
+	return l.load(true)
+}
+
+func (l *Loader) load(overload bool) ([]string, error) {
+	envFiles, err := l.getEnvFiles()
+	if err != nil {
+		return nil, &LoadError{Err: err}
+	}
+	vars, err := l.decryptFiles(envFiles)
+	if err != nil {
+		return nil, &LoadError{Path: pathOf(envFiles, err), Err: err}
+	}
+
+	var applied []string
+	for _, v := range lastWins(vars) {
+		if _, set := os.LookupEnv(v.Name); set && !overload {
+			continue
+		}
+		if err := os.Setenv(v.Name, v.Value); err != nil {
+			return applied, &LoadError{pathOf(envFiles, err), v.Name, err}
+		}
+		applied = append(applied, v.Name)
+	}
+	return applied, nil
+}
+
+// A name assigned twice in one file takes its last value, as in dotenv, but
+// keeps the place of its first.
+func lastWins(vars []EnvVar) []EnvVar {
+	index := make(map[string]int, len(vars))
+	var unique []EnvVar
+	for _, v := range vars {
+		if i, seen := index[v.Name]; seen {
+			unique[i].Value = v.Value
+			continue
+		}
+		index[v.Name] = len(unique)
+		unique = append(unique, v)
+	}
+	return unique
+}
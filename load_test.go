@@ -0,0 +1,110 @@
+package dotenvx
+
+import (
+	"errors"
+	"os"
+	"slices"
+	"testing"
+)
+
+func unsetAfter(t *testing.T, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		os.Unsetenv(name)
+		t.Cleanup(func() { os.Unsetenv(name) })
+	}
+}
+
+func TestLoad_SetsOnlyUnsetVariables(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	setKeys(t, "DOTENV_PRIVATE_KEY")
+	unsetAfter(t, "SECRET", "PLAIN", "ALREADY")
+	os.Setenv("ALREADY", "process")
+
+	os.WriteFile(".env", []byte("SECRET="+testCipher+"\nPLAIN=first\nALREADY=file\nPLAIN=second\n"), 0644)
+
+	applied, err := Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !slices.Equal(applied, []string{"SECRET", "PLAIN"}) {
+		t.Errorf("Expected [SECRET PLAIN] applied, got %v", applied)
+	}
+	if os.Getenv("SECRET") != "hello" || os.Getenv("PLAIN") != "second" || os.Getenv("ALREADY") != "process" {
+		t.Errorf("Expected SECRET=hello PLAIN=second ALREADY=process, got %q %q %q",
+			os.Getenv("SECRET"), os.Getenv("PLAIN"), os.Getenv("ALREADY"))
+	}
+}
+
+func TestOverload_ReplacesSetVariables(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	setKeys(t, "DOTENV_PRIVATE_KEY")
+	unsetAfter(t, "SECRET", "ALREADY")
+	os.Setenv("ALREADY", "process")
+
+	os.WriteFile(".env", []byte("SECRET="+testCipher+"\nALREADY=file\n"), 0644)
+
+	applied, err := Overload()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !slices.Equal(applied, []string{"SECRET", "ALREADY"}) {
+		t.Errorf("Expected [SECRET ALREADY] applied, got %v", applied)
+	}
+	if os.Getenv("ALREADY") != "file" {
+		t.Errorf("Expected ALREADY=file, got %q", os.Getenv("ALREADY"))
+	}
+}
+
+func TestLoad_NoKey(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	clearEnvKeys()
+
+	applied, err := Load()
+	var loadErr *LoadError
+	if !errors.As(err, &loadErr) || loadErr.Name != "" || len(applied) != 0 {
+		t.Errorf("Expected a LoadError and nothing applied, got %v %v", applied, err)
+	}
+	if !errors.Is(err, ErrNoKey) {
+		t.Errorf("Expected the LoadError to wrap ErrNoKey, got %v", err)
+	}
+}
+
+func TestLoad_WrongKeySetsNothing(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	clearEnvKeys()
+	unsetAfter(t, "PLAIN", "SECRET")
+
+	os.WriteFile(".env", []byte("PLAIN=value\nSECRET="+testCipher+"\n"), 0644)
+	t.Setenv("DOTENV_PRIVATE_KEY", prodKeyHex)
+
+	applied, err := Load()
+	var loadErr *LoadError
+	if !errors.As(err, &loadErr) || loadErr.Path != ".env" {
+		t.Fatalf("Expected a LoadError for .env, got %v", err)
+	}
+	if len(applied) != 0 || os.Getenv("PLAIN") != "" {
+		t.Errorf("Expected nothing set, got %v and PLAIN=%q", applied, os.Getenv("PLAIN"))
+	}
+}
+
+func TestLoad_SetenvRefused(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	setKeys(t, "DOTENV_PRIVATE_KEY")
+	unsetAfter(t, "FIRST")
+
+	// The one thing os.Setenv rejects that the parser lets through: a NUL byte
+	os.WriteFile(".env", []byte("FIRST=ok\nBAD=a\x00b\n"), 0644)
+
+	applied, err := Load()
+	var loadErr *LoadError
+	if !errors.As(err, &loadErr) || loadErr.Name != "BAD" {
+		t.Fatalf("Expected a LoadError naming BAD, got %v", err)
+	}
+	if !slices.Equal(applied, []string{"FIRST"}) {
+		t.Errorf("Expected FIRST applied before the failure, got %v", applied)
+	}
+	if loadErr.Error() == "" || loadErr.Unwrap() == nil {
+		t.Error("Expected a message and a cause")
+	}
+}
@@ -0,0 +1,180 @@
+package dotenvx
+
+import (
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Everything the package-level functions take from globals -- the working
+// directory, os.Environ, Logger -- as state of its own, so two environments can
+// be loaded side by side and tests need neither os.Chdir nor os.Setenv. The
+// zero-config Getenv, Environ and DecryptFile are a default Loader.
+type Loader struct {
+	dir            string
+	fsys           fs.FS
+	files          []string
+	keys           []string
+	environ        func() []string
+	logger         *slog.Logger
+	overload       bool
+	reloadOnChange bool
+	lenient        bool
+	secureMemory   bool
+	unsetKeys      bool
+	globals        bool
+
+	mu    sync.RWMutex
+	cache *snapshot
+}
+
+type Option func(*Loader)
+
+// Where env files and .env.keys are looked for, instead of the working directory.
+func WithDir(dir string) Option {
+	return func(l *Loader) { l.dir = dir }
+}
+
+// Read env files and .env.keys from fsys, an embed.FS say, instead of the
+// disk; WithDir is then a directory inside it.
+func WithFS(fsys fs.FS) Option {
+	return func(l *Loader) { l.fsys = fsys }
+}
+
+// Decrypt this file instead of choosing one by key name. Without WithKeys its key
+// is the DOTENV_PRIVATE_KEY* variable named after it: .env.qa.test reads
+// DOTENV_PRIVATE_KEY_QA_TEST. It is WithFiles of one file, so without a key it
+// can still hold plain values.
+func WithFile(path string) Option {
+	return func(l *Loader) { l.files = []string{path} }
+}
+
+// Decrypt and merge these files in order, as dotenvx run -f a -f b does: the
+// first to set a variable wins, unless WithOverload. Each takes the key named
+// after it, as with WithFile, or WithKeys; one without a key can still hold
+// plain values, and one that does not exist is skipped.
+func WithFiles(paths ...string) Option {
+	return func(l *Loader) { l.files = paths }
+}
+
+// With WithFiles, the last file to set a variable wins instead of the first,
+// like dotenvx --overload.
+func WithOverload() Option {
+	return func(l *Loader) { l.overload = true }
+}
+
+// Decrypt with these keys instead of looking for DOTENV_PRIVATE_KEY*; each may
+// itself be a comma-separated list. Without WithFile the file is .env.
+func WithKeys(keyHex ...string) Option {
+	return func(l *Loader) { l.keys = keyHex }
+}
+
+// Where DOTENV_PRIVATE_KEY*, DOTENV_KEYS_PATH and the variables ${...} refers
+// to come from, instead of os.Environ.
+func WithEnviron(environ func() []string) Option {
+	return func(l *Loader) { l.environ = environ }
+}
+
+// Receives the Loader's events; nil, or no WithLogger, discards them.
+func WithLogger(logger *slog.Logger) Option {
+	return func(l *Loader) { l.logger = logger }
+}
+
+// What ReloadOnChange is for the package-level functions.
+func WithReloadOnChange() Option {
+	return func(l *Loader) { l.reloadOnChange = true }
+}
+
+// What Lenient is for the package-level functions.
+func WithLenient() Option {
+	return func(l *Loader) { l.lenient = true }
+}
+
+// What SecureMemory is for the package-level functions.
+func WithSecureMemory() Option {
+	return func(l *Loader) { l.secureMemory = true }
+}
+
+// What UnsetKeys is for the package-level functions.
+func WithUnsetKeys() Option {
+	return func(l *Loader) { l.unsetKeys = true }
+}
+
+// The default Loader answers to the Logger, Debug, Lenient, ReloadOnChange,
+// SecureMemory and UnsetKeys globals instead of options.
+func withGlobals() Option {
+	return func(l *Loader) { l.globals = true }
+}
+
+func New(opts ...Option) *Loader {
+	l := &Loader{environ: os.Environ}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+var defaultLoader = New(withGlobals())
+
+var discardLogger = slog.New(slog.DiscardHandler)
+
+func (l *Loader) log() *slog.Logger {
+	switch {
+	case l.logger != nil:
+		return l.logger
+	case !l.globals || Logger == nil:
+		return discardLogger
+	case Debug && Logger == discardLogger:
+		return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	}
+	return Logger
+}
+
+func (l *Loader) revalidates() bool {
+	return l.reloadOnChange || (l.globals && ReloadOnChange)
+}
+
+func (l *Loader) isLenient() bool {
+	return l.lenient || (l.globals && Lenient)
+}
+
+func (l *Loader) path(name string) string {
+	if l.fsys != nil {
+		return path.Join(l.dir, name)
+	}
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(l.dir, name)
+}
+
+func (l *Loader) readFile(name string) ([]byte, error) {
+	if l.fsys != nil {
+		return fs.ReadFile(l.fsys, name)
+	}
+	return os.ReadFile(name)
+}
+
+func (l *Loader) stat(name string) (fs.FileInfo, error) {
+	if l.fsys != nil {
+		return fs.Stat(l.fsys, name)
+	}
+	return os.Stat(name)
+}
+
+func (l *Loader) lookupEnv() func(string) (string, bool) {
+	env := map[string]string{}
+	for _, kv := range l.environ() {
+		if name, value, ok := strings.Cut(kv, "="); ok {
+			env[name] = value
+		}
+	}
+	return func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+}
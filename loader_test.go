@@ -0,0 +1,217 @@
+package dotenvx
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func environOf(env ...string) func() []string {
+	return func() []string { return env }
+}
+
+func TestLoader_DirAndEnviron(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, ".env"), []byte("SECRET="+testCipher+"\nURL=${SCHEME}://db\n"), 0644)
+
+	l := New(WithDir(dir), WithEnviron(environOf("DOTENV_PRIVATE_KEY="+testKeyHex, "SCHEME=postgres")))
+	if got := l.Getenv("SECRET"); got != "hello" {
+		t.Errorf("Expected 'hello', got %q", got)
+	}
+	if got := l.Getenv("URL"); got != "postgres://db" {
+		t.Errorf("Expected ${SCHEME} from the Loader's environ, got %q", got)
+	}
+	if env, err := l.EnvironStrict(); err != nil || len(env) != 2 {
+		t.Errorf("Expected 2 variables, got %v %v", env, err)
+	}
+}
+
+func TestLoader_KeysFileInDir(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, ".env.production"), []byte("SECRET="+prodCipher+"\n"), 0644)
+	os.WriteFile(filepath.Join(dir, keysFile), []byte("DOTENV_PRIVATE_KEY_PRODUCTION="+prodKeyHex+"\n"), 0600)
+
+	l := New(WithDir(dir), WithEnviron(environOf()))
+	if got := l.Getenv("SECRET"); got != "world" {
+		t.Errorf("Expected 'world', got %q", got)
+	}
+}
+
+func TestLoader_TwoSideBySide(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, ".env"), []byte("SECRET="+testCipher+"\n"), 0644)
+	os.WriteFile(filepath.Join(dir, ".env.production"), []byte("SECRET="+prodCipher+"\n"), 0644)
+
+	dev := New(WithDir(dir), WithKeys(testKeyHex))
+	prod := New(WithDir(dir), WithFile(".env.production"), WithKeys(prodKeyHex))
+	if dev.Getenv("SECRET") != "hello" || prod.Getenv("SECRET") != "world" {
+		t.Errorf("Expected hello and world, got %q and %q", dev.Getenv("SECRET"), prod.Getenv("SECRET"))
+	}
+}
+
+func TestLoader_FileFindsItsKey(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, ".env"), []byte("SECRET="+testCipher+"\n"), 0644)
+	os.WriteFile(filepath.Join(dir, ".env.production"), []byte("SECRET="+prodCipher+"\n"), 0644)
+
+	// Two suffixed keys would be ambiguous without WithFile naming one
+	l := New(WithDir(dir), WithFile(".env.production"), WithEnviron(environOf(
+		"DOTENV_PRIVATE_KEY_PRODUCTION="+prodKeyHex, "DOTENV_PRIVATE_KEY_STAGING="+testKeyHex)))
+	if got := l.Getenv("SECRET"); got != "world" {
+		t.Errorf("Expected 'world', got %q", got)
+	}
+
+	os.WriteFile(filepath.Join(dir, ".env.qa"), []byte("SECRET="+testCipher+"\n"), 0644)
+	l = New(WithDir(dir), WithFile(".env.qa"), WithEnviron(environOf("DOTENV_PRIVATE_KEY="+testKeyHex)))
+	if err := l.Reload(); !errors.Is(err, ErrNoKey) {
+		t.Errorf("Expected ErrNoKey without DOTENV_PRIVATE_KEY_QA, got %v", err)
+	}
+}
+
+// One named file is a layer of one: plain values need no key, and a missing
+// file is skipped, leaving nothing to load.
+func TestLoader_OneFileLikeMany(t *testing.T) {
+	t.Parallel()
+	dir := writeFiles(t, map[string]string{".env.local": "PLAIN=value\n"})
+	for _, l := range []*Loader{
+		New(WithDir(dir), WithFile(".env.local"), WithEnviron(environOf())),
+		New(WithDir(dir), WithFiles(".env.local", ".env.absent"), WithEnviron(environOf())),
+	} {
+		if got, err := l.GetenvStrict("PLAIN"); got != "value" || err != nil {
+			t.Errorf("Expected PLAIN=value without a key, got %q %v", got, err)
+		}
+	}
+	l := New(WithDir(dir), WithFile(".env.absent"), WithEnviron(environOf()))
+	if err := l.Reload(); !errors.Is(err, ErrNoMatchingFile) {
+		t.Errorf("Expected ErrNoMatchingFile for a missing file, got %v", err)
+	}
+}
+
+func TestLoader_InvalidKeys(t *testing.T) {
+	t.Parallel()
+	l := New(WithDir(t.TempDir()), WithKeys("not-hex"))
+	if err := l.Reload(); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected ErrInvalidKey, got %v", err)
+	}
+}
+
+func TestLoader_Logger(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	l := New(WithDir(t.TempDir()), WithEnviron(environOf()), WithLogger(slog.New(slog.NewTextHandler(&out, nil))))
+
+	if got := l.Getenv("SECRET"); got != "" {
+		t.Errorf("Expected \"\" without a key, got %q", got)
+	}
+	if !strings.Contains(out.String(), "No key found") {
+		t.Errorf("Expected 'No key found' logged, got %q", out.String())
+	}
+}
+
+func TestLoader_LogsNamesNotValues(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, ".env"), []byte("PLAIN=visible\nSECRET="+testCipher+"\nBROKEN=encrypted:AAAA\n"), 0644)
+
+	var out bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	l := New(WithDir(dir), WithEnviron(environOf("DOTENV_PRIVATE_KEY="+testKeyHex)), WithLogger(logger))
+	l.Getenv("SECRET")
+
+	events := map[string]map[string]any{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var event map[string]any
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("Expected JSON lines, got %q: %v", line, err)
+		}
+		events[event["msg"].(string)+" "+fmt.Sprint(event["var"])] = event
+	}
+	if e := events["key selected <nil>"]; e == nil || e["key_var"] != "DOTENV_PRIVATE_KEY" || e["file"] != filepath.Join(dir, ".env") {
+		t.Errorf("Expected a key selected event with key_var and file, got %v", events)
+	}
+	if e := events["decrypted SECRET"]; e == nil || e["level"] != "DEBUG" || e["line"] != 2.0 {
+		t.Errorf("Expected SECRET decrypted on line 2, got %v", events)
+	}
+	if e := events["decrypt failed BROKEN"]; e == nil || e["level"] != "WARN" {
+		t.Errorf("Expected BROKEN to fail as a warning, got %v", events)
+	}
+	if strings.Contains(out.String(), "hello") || strings.Contains(out.String(), testKeyHex) {
+		t.Errorf("Expected neither a value nor the key in the log, got %s", out.String())
+	}
+}
+
+func TestLoader_IgnoresProcessGlobals(t *testing.T) {
+	defer os.Chdir(inTempDir(t))
+	setKeys(t, "DOTENV_PRIVATE_KEY")
+	os.WriteFile(".env", []byte("SECRET="+testCipher+"\n"), 0644)
+
+	l := New(WithEnviron(environOf()))
+	if got := l.Getenv("SECRET"); got != "" {
+		t.Errorf("Expected the process's key to be invisible to the Loader, got %q", got)
+	}
+	if got := Getenv("SECRET"); got != "hello" {
+		t.Errorf("Expected the package-level Getenv to still see it, got %q", got)
+	}
+}
+
+func TestLoader_ReloadOnChange(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".env")
+	os.WriteFile(path, []byte("PLAIN=one\n"), 0644)
+
+	l := New(WithDir(dir), WithKeys(testKeyHex), WithReloadOnChange())
+	if got := l.Getenv("PLAIN"); got != "one" {
+		t.Fatalf("Expected 'one', got %q", got)
+	}
+	os.WriteFile(path, []byte("PLAIN=two\n"), 0644)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(path, later, later)
+	if got := l.Getenv("PLAIN"); got != "two" {
+		t.Errorf("Expected 'two' after the file changed, got %q", got)
+	}
+}
+
+func TestLoader_WithLenient(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, ".env"), []byte("PLAIN=plain\nSECRET="+testCipher+"\n"), 0644)
+
+	strict := New(WithDir(dir), WithKeys(prodKeyHex))
+	lenient := New(WithDir(dir), WithKeys(prodKeyHex), WithLenient())
+	if strict.Getenv("PLAIN") != "" || lenient.Getenv("PLAIN") != "plain" {
+		t.Errorf("Expected \"\" strict and plain lenient, got %q and %q", strict.Getenv("PLAIN"), lenient.Getenv("PLAIN"))
+	}
+}
+
+func TestLoader_WithFS(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"config/.env.production": {Data: []byte("SECRET=" + prodCipher + "\n")},
+		"config/.env.keys":       {Data: []byte("DOTENV_PRIVATE_KEY_PRODUCTION=" + prodKeyHex + "\n")},
+	}
+	l := New(WithFS(fsys), WithDir("config"), WithEnviron(environOf()))
+	if got := l.Getenv("SECRET"); got != "world" {
+		t.Errorf("Expected the file and its key found inside the FS, got %q", got)
+	}
+
+	l = New(WithFS(embedded), WithEnviron(environOf("DOTENV_PRIVATE_KEY="+testKeyHex)))
+	if got := l.Getenv("GREETING"); got != "hello" {
+		t.Errorf("Expected GREETING=hello from the embedded .env, got %q", got)
+	}
+	if vars, err := l.DecryptFile(".env.production", prodKeyHex); err != nil || vars[len(vars)-1].Value != "world" {
+		t.Errorf("Expected DecryptFile to read inside the FS, got %+v %v", vars, err)
+	}
+}
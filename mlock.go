@@ -0,0 +1,9 @@
+//go:build linux || darwin
+
+package dotenvx
+
+import "syscall"
+
+func mlock(b []byte) error { return syscall.Mlock(b) }
+
+func munlock(b []byte) error { return syscall.Munlock(b) }
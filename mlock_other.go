@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+
+package dotenvx
+
+import "errors"
+
+func mlock(b []byte) error { return errors.New("mlock is not supported on this platform") }
+
+func munlock(b []byte) error { return nil }
@@ -0,0 +1,181 @@
+package dotenvx
+
+import "strings"
+
+type envEntry struct {
+	name  string
+	value string
+	quote byte // ', " or `, or 0 when the value was written bare
+	line  int  // where the assignment starts, counting from 1
+
+	encrypted bool // decrypted, or expanded from a value that was
+
+	// Byte offsets of the name, and of the value as written, quotes included,
+	// so a writer can replace either without disturbing the rest of the file.
+	nameStart  int
+	valueStart int
+	valueEnd   int
+}
+
+// Tokenizes the dotenv grammar the way dotenvx reads it:
+//
+//	[export] KEY = value [# comment]     KEY: value is accepted too
+//
+// Double-quoted values may span lines and expand \n, \r, \t, \" and \\; single-
+// and backtick-quoted values may span lines and are taken literally; bare
+// values end at the first # or newline and are trimmed. A quote that is never
+// closed, or is followed by anything but a comment, makes the value bare.
+// Lines that are not assignments are skipped, as dotenv skips what its LINE
+// regexp does not match.
+func parseEnv(src string) []envEntry {
+	p := envParser{src: src, line: 1}
+	var entries []envEntry
+	for p.pos < len(p.src) {
+		if entry, ok := p.assignment(); ok {
+			entries = append(entries, entry)
+		}
+		p.advance(p.lineEnd(p.pos) + 1)
+	}
+	return entries
+}
+
+type envParser struct {
+	src  string
+	pos  int
+	line int
+}
+
+func (p *envParser) advance(to int) {
+	to = min(to, len(p.src))
+	p.line += strings.Count(p.src[p.pos:to], "\n")
+	p.pos = to
+}
+
+func (p *envParser) lineEnd(from int) int {
+	if end := strings.IndexByte(p.src[from:], '\n'); end >= 0 {
+		return from + end
+	}
+	return len(p.src)
+}
+
+func (p *envParser) skipBlanks() {
+	for p.pos < len(p.src) && isBlank(p.src[p.pos]) {
+		p.pos++
+	}
+}
+
+func isBlank(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isKeyByte(c byte) bool {
+	return c == '_' || c == '.' || c == '-' ||
+		('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+func (p *envParser) assignment() (entry envEntry, ok bool) {
+	p.skipBlanks()
+	if rest := p.src[p.pos:]; strings.HasPrefix(rest, "export") && len(rest) > 6 && isBlank(rest[6]) {
+		p.pos += len("export")
+		p.skipBlanks()
+	}
+	keyStart := p.pos
+	for p.pos < len(p.src) && isKeyByte(p.src[p.pos]) {
+		p.pos++
+	}
+	entry.name, entry.line, entry.nameStart = p.src[keyStart:p.pos], p.line, keyStart
+	if entry.name == "" {
+		return entry, false
+	}
+	p.skipBlanks()
+	switch {
+	case p.pos < len(p.src) && p.src[p.pos] == '=':
+		p.pos++
+	case p.pos+1 < len(p.src) && p.src[p.pos] == ':' && isBlank(p.src[p.pos+1]):
+		p.pos++
+	default:
+		return entry, false
+	}
+	p.skipBlanks()
+
+	entry.valueStart = p.pos
+	if p.quoted(&entry) {
+		return entry, true
+	}
+	p.bare(&entry)
+	return entry, true
+}
+
+func (p *envParser) quoted(entry *envEntry) bool {
+	if p.pos >= len(p.src) || !strings.ContainsRune("'\"`", rune(p.src[p.pos])) {
+		return false
+	}
+	quote := p.src[p.pos]
+	closing := -1
+	for i := p.pos + 1; i < len(p.src) && closing < 0; i++ {
+		switch {
+		case p.src[i] == '\\' && i+1 < len(p.src) && p.src[i+1] == quote:
+			i++
+		case p.src[i] == quote:
+			closing = i
+		}
+	}
+	if closing < 0 {
+		return false
+	}
+	trailer := strings.TrimLeft(p.src[closing+1:p.lineEnd(closing)], " \t\r\f\v")
+	if trailer != "" && trailer[0] != '#' {
+		return false
+	}
+
+	entry.quote, entry.valueEnd = quote, closing+1
+	entry.value = unquote(p.src[entry.valueStart+1:closing], quote)
+	p.advance(closing)
+	return true
+}
+
+func (p *envParser) bare(entry *envEntry) {
+	end := p.lineEnd(p.pos)
+	if comment := strings.IndexByte(p.src[p.pos:end], '#'); comment >= 0 {
+		end = p.pos + comment
+	}
+	value := strings.TrimRight(p.src[p.pos:end], " \t\r\f\v")
+	entry.valueEnd = entry.valueStart + len(value)
+
+	// What dotenv does after its regexp: a value that still starts and ends with
+	// the same quote, like "a" b", loses them and is treated as quoted
+	if len(value) >= 2 && strings.ContainsRune("'\"`", rune(value[0])) && value[len(value)-1] == value[0] {
+		entry.quote = value[0]
+		value = unquote(value[1:len(value)-1], value[0])
+	}
+	entry.value = value
+}
+
+func unquote(raw string, quote byte) string {
+	raw = strings.ReplaceAll(raw, "\r\n", "\n")
+	if quote != '"' || !strings.Contains(raw, `\`) {
+		return raw
+	}
+	var value strings.Builder
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != '\\' || i+1 == len(raw) {
+			value.WriteByte(raw[i])
+			continue
+		}
+		switch raw[i+1] {
+		case 'n':
+			value.WriteByte('\n')
+		case 'r':
+			value.WriteByte('\r')
+		case 't':
+			value.WriteByte('\t')
+		case '"', '\\':
+			value.WriteByte(raw[i+1])
+		default:
+			// Anything else, \$ included, is kept whole for later stages
+			value.WriteString(raw[i : i+2])
+		}
+		i++
+	}
+	return value.String()
+}
@@ -0,0 +1,131 @@
+package dotenvx
+
+import (
+	"strings"
+	"testing"
+)
+
+// Cases from dotenv's own test fixtures, which dotenvx's parser is held to,
+// plus the escapes and malformed lines dotenvx resolves beyond them.
+var parseConformance = []struct {
+	name string
+	src  string
+	want string
+}{
+	{"basic", "BASIC=basic", "basic"},
+	{"empty", "EMPTY=", ""},
+	{"empty single quotes", "EMPTY=''", ""},
+	{"empty double quotes", `EMPTY=""`, ""},
+	{"empty backticks", "EMPTY=``", ""},
+	{"single quotes", "Q='single_quotes'", "single_quotes"},
+	{"single quotes spaced", "Q='    single quotes    '", "    single quotes    "},
+	{"double quotes", `Q="double_quotes"`, "double_quotes"},
+	{"double quotes spaced", `Q="    double quotes    "`, "    double quotes    "},
+	{"double inside single", `Q='double "quotes" work inside single quotes'`, `double "quotes" work inside single quotes`},
+	{"braces in double", `Q="{ port: $MONGOLAB_PORT}"`, "{ port: $MONGOLAB_PORT}"},
+	{"single inside double", `Q="single 'quotes' work inside double quotes"`, "single 'quotes' work inside double quotes"},
+	{"backticks inside single", "Q='`backticks` work inside single quotes'", "`backticks` work inside single quotes"},
+	{"backticks inside double", "Q=\"`backticks` work inside double quotes\"", "`backticks` work inside double quotes"},
+	{"backticks", "Q=`backticks`", "backticks"},
+	{"backticks spaced", "Q=`    backticks    `", "    backticks    "},
+	{"both quotes inside backticks", "Q=`double \"quotes\" and single 'quotes' work inside backticks`", `double "quotes" and single 'quotes' work inside backticks`},
+	{"expand newlines", `Q="expand\nnew\nlines"`, "expand\nnew\nlines"},
+	{"unquoted keeps backslash n", `Q=dontexpand\nnewlines`, `dontexpand\nnewlines`},
+	{"single quoted keeps backslash n", `Q='dontexpand\nnewlines'`, `dontexpand\nnewlines`},
+	{"inline comments", "Q=inline comments # work #very #well", "inline comments"},
+	{"inline comment after single quotes", "Q='inline comments outside of #singlequotes' # work", "inline comments outside of #singlequotes"},
+	{"inline comment after double quotes", `Q="inline comments outside of #doublequotes" # work`, "inline comments outside of #doublequotes"},
+	{"inline comment after backticks", "Q=`inline comments outside of #backticks` # work", "inline comments outside of #backticks"},
+	{"inline comment needs no space", "Q=inline comments start with a#number sign. no space required.", "inline comments start with a"},
+	{"equal signs", "Q=equals==", "equals=="},
+	{"retain inner quotes", `Q={"foo": "bar"}`, `{"foo": "bar"}`},
+	{"retain inner quotes as string", `Q='{"foo": "bar"}'`, `{"foo": "bar"}`},
+	{"retain inner quotes in backticks", "Q=`{\"foo\": \"bar's\"}`", `{"foo": "bar's"}`},
+	{"trim unquoted", "Q=    some spaced out string   ", "some spaced out string"},
+	{"email", "Q=therealnerdybeast@example.tld", "therealnerdybeast@example.tld"},
+	{"spaced key", "    Q = parsed", "parsed"},
+	{"tab around equals", "Q\t=\tparsed", "parsed"},
+	{"export", "export Q=exported", "exported"},
+	{"export with spaces", "  export   Q = exported", "exported"},
+	{"yaml style", "Q: yaml", "yaml"},
+	{"crlf", "Q=crlf\r\n", "crlf"},
+	{"crlf double quoted", "Q=\"a\r\nb\"\r\n", "a\nb"},
+	{"multiline double", "Q=\"THIS\nIS\nA\nMULTILINE\nSTRING\"", "THIS\nIS\nA\nMULTILINE\nSTRING"},
+	{"multiline single", "Q='THIS\nIS\nA\nMULTILINE\nSTRING'", "THIS\nIS\nA\nMULTILINE\nSTRING"},
+	{"multiline backticks", "Q=`THIS\nIS\nA\n\"MULTILINE'S\"\nSTRING`", "THIS\nIS\nA\n\"MULTILINE'S\"\nSTRING"},
+	{"pem", "Q=\"-----BEGIN PUBLIC KEY-----\nMIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEAnNl1tL3QjKp3DZWM0T3u\n-----END PUBLIC KEY-----\"", "-----BEGIN PUBLIC KEY-----\nMIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEAnNl1tL3QjKp3DZWM0T3u\n-----END PUBLIC KEY-----"},
+	{"escaped double quotes", `Q="escaped \"double\" quotes"`, `escaped "double" quotes`},
+	{"escaped tab and backslash", `Q="a\tb\\c"`, "a\tb\\c"},
+	{"escaped dollar kept", `Q="\$HOME"`, `\$HOME`},
+	{"escaped single quote in single", `Q='it\'s'`, `it\'s`},
+	{"unterminated quote is bare", `Q="unterminated`, `"unterminated`},
+	{"text after quote is bare", `Q="a" b`, `"a" b`},
+	{"text after quote ending in quote", `Q="a" b"`, `a" b`},
+	{"encrypted", "Q=\"encrypted:BL8c+/=\"", "encrypted:BL8c+/="},
+}
+
+func TestParseEnv_Conformance(t *testing.T) {
+	for _, tc := range parseConformance {
+		entries := parseEnv(tc.src)
+		if len(entries) != 1 {
+			t.Errorf("%s: expected 1 entry from %q, got %+v", tc.name, tc.src, entries)
+			continue
+		}
+		if entries[0].value != tc.want {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.want, entries[0].value)
+		}
+	}
+}
+
+func TestParseEnv_SkipsWhatIsNotAnAssignment(t *testing.T) {
+	src := "# COMMENTS=work\n\n   \nJUST_A_WORD\n=value\nexport\nexport=not a prefix\nKEY:novalue\nBAD KEY=x\nOK=yes\n"
+
+	entries := parseEnv(src)
+	if len(entries) != 2 {
+		t.Fatalf("Expected export and OK, got %+v", entries)
+	}
+	if entries[0].name != "export" || entries[0].value != "not a prefix" {
+		t.Errorf("Expected export=not a prefix, got %+v", entries[0])
+	}
+	if entries[1].name != "OK" || entries[1].value != "yes" {
+		t.Errorf("Expected OK=yes, got %+v", entries[1])
+	}
+}
+
+func TestParseEnv_LinesAndOffsets(t *testing.T) {
+	src := "# header\nFIRST=\"one\nstill one\" # comment\n\nexport SECOND = two  # comment\nTHIRD='3'"
+
+	entries := parseEnv(src)
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %+v", entries)
+	}
+	for i, want := range []struct {
+		name, raw string
+		line      int
+		quote     byte
+	}{
+		{"FIRST", "\"one\nstill one\"", 2, '"'},
+		{"SECOND", "two", 5, 0},
+		{"THIRD", "'3'", 6, '\''},
+	} {
+		entry := entries[i]
+		if entry.name != want.name || entry.line != want.line || entry.quote != want.quote {
+			t.Errorf("Expected %s on line %d quoted %q, got %+v", want.name, want.line, want.quote, entry)
+		}
+		if raw := src[entry.valueStart:entry.valueEnd]; raw != want.raw {
+			t.Errorf("%s: expected the value as written %q, got %q", want.name, want.raw, raw)
+		}
+	}
+}
+
+func TestParseEnv_RepoFixtures(t *testing.T) {
+	for _, fixture := range fixtures {
+		vars, err := DecryptFile(fixture.path, fixture.keyHex)
+		if err != nil {
+			t.Fatalf("%s: %v", fixture.path, err)
+		}
+		if len(vars) != 2 || !strings.HasPrefix(vars[0].Name, "DOTENV_PUBLIC_KEY") || vars[1].Name != "GREETING" {
+			t.Errorf("%s: expected the header key and GREETING, got %+v", fixture.path, vars)
+		}
+	}
+}
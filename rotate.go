@@ -0,0 +1,132 @@
+package dotenvx
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	ecies "github.com/ecies/go/v2"
+)
+
+// A new keypair for one env file and everything that changes with it, worked
+// out in memory so it can be shown before Apply writes a byte. Names are the
+// variables re-encrypted; the private key itself is never exposed.
+type Rotation struct {
+	Path          string
+	KeysPath      string
+	PublicKeyVar  string
+	PrivateKeyVar string
+	PublicKey     string
+	Names         []string
+
+	// The old key came from the environment, which Apply cannot update
+	KeyFromEnvironment bool
+
+	content []byte
+	mode    os.FileMode
+	oldKeys string
+	newKey  string
+}
+
+// Decrypts every encrypted value in the file at path with its current key,
+// found the way Getenv finds it, and re-encrypts it to a fresh keypair. The
+// values are re-encrypted as written: ${...} in them stays unexpanded.
+func PlanRotation(path string) (*Rotation, error) {
+	key, keysPath, err := fileKey(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &Rotation{Path: path, KeysPath: keysPath, PrivateKeyVar: key.varName, oldKeys: key.keyHex, KeyFromEnvironment: key.source == "environment"}
+	oldKeys, err := parsePrivateKeys(r.oldKeys)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", r.PrivateKeyVar, err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	entries := parseEnv(string(content))
+	if err := checkPublicKey(entries, oldKeys); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	header := slices.IndexFunc(entries, func(e envEntry) bool { return isPublicKeyVar(e.name) })
+	if header < 0 {
+		return nil, fmt.Errorf("%s: no %s header", path, publicKeyVar)
+	}
+
+	privateKey, err := ecies.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	r.newKey = hex.EncodeToString(privateKey.D.FillBytes(make([]byte, 32)))
+	r.PublicKey = privateKey.PublicKey.Hex(true)
+	r.PublicKeyVar = entries[header].name
+
+	// Node by node rather than Set, so a variable assigned twice keeps each
+	// value it had
+	doc := ParseDocument(content)
+	seenHeader := false
+	for i, node := range doc.nodes {
+		switch {
+		case node.Kind != AssignmentNode:
+		case !seenHeader && node.Name == r.PublicKeyVar:
+			doc.setValue(i, r.PublicKey)
+			seenHeader = true
+		case strings.HasPrefix(node.Value, encryptedPrefix):
+			plain, err := decryptSecretStrict(oldKeys, node.Value[len(encryptedPrefix):])
+			if err != nil {
+				return nil, &DecryptError{path, node.Line, node.Name, err}
+			}
+			value, err := EncryptValue(r.PublicKey, plain)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			doc.setValue(i, value)
+			r.Names = append(r.Names, node.Name)
+		}
+	}
+	r.content, r.mode = doc.Bytes(), info.Mode().Perm()
+	return r, nil
+}
+
+// Writes the rotation so that a crash at any point leaves the file readable:
+// .env.keys first holds the new key beside the old one, then the file is
+// replaced, then the old key is dropped. Each write is a rename over the
+// original.
+func (r *Rotation) Apply() error {
+	if err := r.writeKeys(r.newKey + "," + r.oldKeys); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(r.Path, r.content, r.mode); err != nil {
+		return err
+	}
+	return r.writeKeys(r.newKey)
+}
+
+func (r *Rotation) writeKeys(keyHex string) error {
+	keys, _, err := readIfExists(r.KeysPath, 0600)
+	if err != nil {
+		return err
+	}
+	doc := ParseDocument(keys)
+	if _, ok := doc.Get(r.PrivateKeyVar); ok {
+		doc.Set(r.PrivateKeyVar, keyHex)
+		keys = doc.Bytes()
+	} else {
+		if len(keys) == 0 {
+			keys = []byte(keysHeader)
+		} else if keys[len(keys)-1] != '\n' {
+			keys = append(keys, '\n')
+		}
+		keys = fmt.Appendf(keys, "\n# %s\n%s=%s\n", filepath.Base(r.Path), r.PrivateKeyVar, keyHex)
+	}
+	return writeFileAtomic(r.KeysPath, keys, 0600)
+}
@@ -0,0 +1,94 @@
+package dotenvx
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ecies "github.com/ecies/go/v2"
+)
+
+func TestRotation(t *testing.T) {
+	clearEnvKeys()
+	fixture, _ := embedded.ReadFile(".env.production")
+	header, _ := filePublicKey(".env.production")
+	template, _ := EncryptValue(header, "https://${ROTATE_TEST_UNSET}/x")
+	dir := writeFiles(t, map[string]string{
+		".env.production": string(fixture) + "export URL=\"" + template + "\" # kept\nPLAIN=plain\n",
+		".env.keys":       "# keys\nDOTENV_PRIVATE_KEY_PRODUCTION=\"" + prodKeyHex + "\"\nDOTENV_PRIVATE_KEY_OTHER=other\n",
+	})
+	path := filepath.Join(dir, ".env.production")
+	os.Chmod(path, 0640)
+
+	rotation, err := PlanRotation(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(rotation.Names, ","); got != "GREETING,URL" || rotation.PublicKeyVar != "DOTENV_PUBLIC_KEY_PRODUCTION" || rotation.KeyFromEnvironment {
+		t.Errorf("Expected GREETING and URL re-encrypted under the header, got %+v", rotation)
+	}
+	if after, _ := os.ReadFile(path); string(after) != string(fixture)+"export URL=\""+template+"\" # kept\nPLAIN=plain\n" {
+		t.Error("Expected planning to write nothing")
+	}
+
+	if err := rotation.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	content, _ := os.ReadFile(path)
+	keys, _ := os.ReadFile(filepath.Join(dir, ".env.keys"))
+	newKey, _ := ParseDocument(keys).Get("DOTENV_PRIVATE_KEY_PRODUCTION")
+	if !strings.HasPrefix(string(keys), "# keys\nDOTENV_PRIVATE_KEY_PRODUCTION=\"") || !strings.HasSuffix(string(keys), "\"\nDOTENV_PRIVATE_KEY_OTHER=other\n") || strings.Contains(string(keys), prodKeyHex) {
+		t.Errorf("Expected only the production key replaced, got:\n%s", keys)
+	}
+	if !strings.HasPrefix(string(content), publicKeyHeader) || !strings.Contains(string(content), `DOTENV_PUBLIC_KEY_PRODUCTION="`+rotation.PublicKey+`"`) ||
+		!strings.Contains(string(content), "export URL=\"encrypted:") || !strings.HasSuffix(string(content), "\" # kept\nPLAIN=plain\n") {
+		t.Errorf("Expected the file rewritten in place, got:\n%s", content)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0640 {
+		t.Errorf("Expected the mode kept, got %v", info.Mode())
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, ".*.*[0-9]")); len(matches) != 0 {
+		t.Errorf("Expected no temporary files left, got %v", matches)
+	}
+
+	vars, err := DecryptFile(path, newKey)
+	if err != nil || vars[1].Value != "world" || vars[2].Value != "https:///x" {
+		t.Errorf("Expected the new key to decrypt, got %q %q %v", vars[1].Value, vars[2].Value, err)
+	}
+	raw, _ := decryptSecretStrict(mustKeys(t, newKey), parseEnv(string(content))[2].value[len(encryptedPrefix):])
+	if raw != "https://${ROTATE_TEST_UNSET}/x" {
+		t.Errorf("Expected the value re-encrypted unexpanded, got %q", raw)
+	}
+	if _, err := DecryptFile(path, prodKeyHex); !errors.Is(err, ErrKeyMismatch) {
+		t.Errorf("Expected the old key rejected, got %v", err)
+	}
+}
+
+func mustKeys(t *testing.T, keyHex string) []*ecies.PrivateKey {
+	t.Helper()
+	keys, err := parsePrivateKeys(keyHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func TestRotation_WrongKeyWritesNothing(t *testing.T) {
+	clearEnvKeys()
+	fixture, _ := embedded.ReadFile(".env.production")
+	dir := writeFiles(t, map[string]string{
+		".env.production": string(fixture),
+		".env.keys":       "DOTENV_PRIVATE_KEY_PRODUCTION=" + testKeyHex + "\n",
+	})
+	if _, err := PlanRotation(filepath.Join(dir, ".env.production")); !errors.Is(err, ErrKeyMismatch) {
+		t.Errorf("Expected ErrKeyMismatch, got %v", err)
+	}
+	if _, err := PlanRotation(filepath.Join(dir, ".env.staging")); !errors.Is(err, ErrNoKey) {
+		t.Errorf("Expected ErrNoKey without DOTENV_PRIVATE_KEY_STAGING, got %v", err)
+	}
+	if _, err := PlanRotation(filepath.Join(dir, "config")); err == nil {
+		t.Error("Expected an error for a file dotenvx would not name")
+	}
+}
@@ -0,0 +1,71 @@
+package dotenvx
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+)
+
+const redacted = "[REDACTED]"
+
+// A decrypted value that prints, marshals and logs as [REDACTED], so a config
+// struct can go to fmt, encoding/json or slog whole. Reveal is the only way to
+// the value; Unmarshal fills Secret fields like string ones.
+type Secret struct {
+	value string
+}
+
+func (s Secret) Reveal() string { return s.value }
+
+func (s Secret) String() string { return redacted }
+
+func (s Secret) GoString() string { return "dotenvx.Secret{" + redacted + "}" }
+
+// fmt prefers Format to String, and would otherwise print the value for %x.
+func (s Secret) Format(f fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && f.Flag('#'):
+		f.Write([]byte(s.GoString()))
+	case verb == 'q':
+		f.Write([]byte(strconv.Quote(redacted)))
+	default:
+		f.Write([]byte(redacted))
+	}
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(redacted)), nil
+}
+
+func (s Secret) LogValue() slog.Value { return slog.StringValue(redacted) }
+
+// What %v of an EnvVar would print, with the value swapped for a Secret when it
+// is one.
+type envVarFields struct {
+	Name      string
+	Value     any
+	Encrypted bool
+}
+
+func (v EnvVar) printable() envVarFields {
+	if v.Encrypted {
+		return envVarFields{v.Name, Secret{v.Value}, true}
+	}
+	return envVarFields{v.Name, v.Value, false}
+}
+
+// Prints as the struct would, but with an encrypted Value redacted, so
+// fmt.Printf("%+v", vars) is safe to leave in.
+func (v EnvVar) Format(f fmt.State, verb rune) {
+	p := v.printable()
+	if verb == 'v' && f.Flag('#') {
+		fmt.Fprintf(f, "dotenvx.EnvVar{Name:%#v, Value:%#v, Encrypted:%t}", p.Name, p.Value, p.Encrypted)
+		return
+	}
+	fmt.Fprintf(f, fmt.FormatString(f, verb), p)
+}
+
+func (v EnvVar) LogValue() slog.Value {
+	p := v.printable()
+	return slog.GroupValue(slog.String("name", p.Name), slog.Any("value", p.Value), slog.Bool("encrypted", p.Encrypted))
+}
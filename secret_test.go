@@ -0,0 +1,72 @@
+package dotenvx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSecret_Redacts(t *testing.T) {
+	s := Secret{"hunter2"}
+	for _, format := range []string{"%v", "%+v", "%#v", "%s", "%q", "%x", "%10s"} {
+		if got := fmt.Sprintf(format, s); strings.Contains(got, "hunter2") || !strings.Contains(got, redacted) {
+			t.Errorf("Expected %s to redact, got %q", format, got)
+		}
+	}
+	if got, _ := json.Marshal(struct{ Password Secret }{s}); string(got) != `{"Password":"[REDACTED]"}` {
+		t.Errorf("Expected JSON redacted, got %s", got)
+	}
+	var buf bytes.Buffer
+	slog.New(slog.NewTextHandler(&buf, nil)).Info("config", "password", s)
+	if strings.Contains(buf.String(), "hunter2") {
+		t.Errorf("Expected the log redacted, got %s", buf.String())
+	}
+	if s.Reveal() != "hunter2" {
+		t.Errorf("Expected Reveal to return the value, got %q", s.Reveal())
+	}
+}
+
+func TestEnvVar_RedactsEncryptedValues(t *testing.T) {
+	vars, err := Decrypt(strings.NewReader("SECRET="+testCipher+"\nURL=postgres://u:${SECRET}@db\nPLAIN=plain\n"), testKeyHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !vars[0].Encrypted || !vars[1].Encrypted || vars[2].Encrypted {
+		t.Errorf("Expected SECRET and the URL expanding it marked encrypted, got %+v", vars)
+	}
+	for _, format := range []string{"%v", "%+v", "%#v", "%s"} {
+		if got := fmt.Sprintf(format, vars); strings.Contains(got, "hello") || !strings.Contains(got, "plain") {
+			t.Errorf("Expected %s to redact only encrypted values, got %s", format, got)
+		}
+	}
+	if got := fmt.Sprintf("%+v", vars[2]); got != "{Name:PLAIN Value:plain Encrypted:false}" {
+		t.Errorf("Expected a plain EnvVar printed as a struct, got %s", got)
+	}
+
+	var buf bytes.Buffer
+	slog.New(slog.NewJSONHandler(&buf, nil)).Info("vars", "var", vars[0])
+	if strings.Contains(buf.String(), "hello") || !strings.Contains(buf.String(), `"name":"SECRET"`) {
+		t.Errorf("Expected the log to name SECRET but redact it, got %s", buf.String())
+	}
+}
+
+func TestUnmarshal_Secret(t *testing.T) {
+	t.Parallel()
+	dir := writeFiles(t, map[string]string{".env": "PASSWORD=" + testCipher + "\nTOKENS=a,b\n"})
+	var cfg struct {
+		Password Secret   `env:"PASSWORD"`
+		Tokens   []Secret `env:"TOKENS"`
+	}
+	if err := New(WithDir(dir), WithKeys(testKeyHex), WithEnviron(environOf())).Unmarshal(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Password.Reveal() != "hello" || len(cfg.Tokens) != 2 || cfg.Tokens[1].Reveal() != "b" {
+		t.Errorf("Expected Secret fields filled, got %q %d", cfg.Password.Reveal(), len(cfg.Tokens))
+	}
+	if got := fmt.Sprintf("%+v", cfg); strings.Contains(got, "hello") {
+		t.Errorf("Expected the config to print redacted, got %s", got)
+	}
+}
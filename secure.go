@@ -0,0 +1,171 @@
+package dotenvx
+
+import (
+	"math/big"
+	"os"
+	"strings"
+	"unsafe"
+
+	ecies "github.com/ecies/go/v2"
+)
+
+// When true, the package-level functions keep the private keys and decrypted
+// values in memory locked out of swap where the platform allows, and zero it
+// once they are done with it: keys as soon as the file is decrypted, values on
+// Reload and Close. Values are decrypted straight into that memory, and a
+// string Getenv returned is a view of it that reads as NUL bytes afterwards;
+// see Getenv. Go strings cannot be wiped, so the copies made on the way in --
+// the keys' hex, a value as it is expanded, Environ's NAME=value lines -- are
+// left to the garbage collector.
+var SecureMemory bool
+
+// When true, the DOTENV_PRIVATE_KEY* variables are removed from the process
+// environment once a file has been decrypted with them, so commands started
+// afterwards do not inherit them. A later Reload then finds only .env.keys.
+var UnsetKeys bool
+
+func (l *Loader) securesMemory() bool {
+	return l.secureMemory || (l.globals && SecureMemory)
+}
+
+func (l *Loader) unsetsKeys() bool {
+	return l.unsetKeys || (l.globals && UnsetKeys)
+}
+
+// Wipes the snapshot Getenv and Environ read from. A later call loads afresh.
+func Close() error {
+	return defaultLoader.Close()
+}
+
+func (l *Loader) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.cache != nil {
+		l.cache.wipe()
+		l.cache = nil
+	}
+	return nil
+}
+
+func zeroKeys(envFiles []EnvFile) {
+	for _, envFile := range envFiles {
+		for _, key := range envFile.Keys {
+			bits := key.D.Bits()
+			clear(bits)
+			munlock(wordBytes(bits))
+		}
+	}
+}
+
+// Moves each key's scalar into a chunk of its own, locked, and zeroes where it
+// was; a chunk apiece so zeroKeys unlocking one never unlocks another's page.
+// Keys shared by several files are moved once.
+func (l *Loader) lockKeys(envFiles []EnvFile) {
+	moved := map[*ecies.PrivateKey]bool{}
+	for _, envFile := range envFiles {
+		for _, key := range envFile.Keys {
+			bits := key.D.Bits()
+			if moved[key] || len(bits) == 0 {
+				continue
+			}
+			moved[key] = true
+			chunk := make([]byte, arenaChunk)
+			words := unsafe.Slice((*big.Word)(unsafe.Pointer(&chunk[0])), len(bits))
+			if err := mlock(wordBytes(words)); err != nil {
+				l.log().Warn("key memory not locked", "file", envFile.Path, "error", err)
+			}
+			copy(words, bits)
+			clear(bits)
+			key.D.SetBits(words)
+		}
+	}
+}
+
+func wordBytes(words []big.Word) []byte {
+	return unsafe.Slice((*byte)(unsafe.Pointer(unsafe.SliceData(words))), len(words)*int(unsafe.Sizeof(big.Word(0))))
+}
+
+func (l *Loader) unsetKeyVars() {
+	if !l.unsetsKeys() {
+		return
+	}
+	for _, env := range os.Environ() {
+		if name, _, _ := strings.Cut(env, "="); strings.HasPrefix(name, keyVar) {
+			os.Unsetenv(name)
+			l.log().Debug("key unset", "key_var", name)
+		}
+	}
+}
+
+// Moves the values expanded from encrypted ones into locked memory; the
+// encrypted values themselves were decrypted there.
+func (l *Loader) seal(s *snapshot) {
+	for i, v := range s.vars {
+		if v.Encrypted && !s.arena.holds(v.Value) {
+			s.vars[i].Value = s.arena.add(v.Value)
+		}
+	}
+	if s.arena.lockErr != nil {
+		l.log().Warn("memory not locked", "error", s.arena.lockErr)
+	}
+}
+
+func (s *snapshot) wipe() {
+	if s.arena != nil {
+		s.arena.wipe()
+	}
+	zeroKeys(s.envFiles)
+}
+
+// Large enough that most files fit in one, and page-aligned as Go allocates
+// anything over 32KiB, so unlocking one chunk never unlocks another's page.
+const arenaChunk = 64 << 10
+
+type arena struct {
+	chunks  [][]byte
+	lockErr error
+}
+
+func (a *arena) add(value string) string {
+	if value == "" {
+		return ""
+	}
+	last := len(a.chunks) - 1
+	if last < 0 || cap(a.chunks[last])-len(a.chunks[last]) < len(value) {
+		chunk := make([]byte, max(arenaChunk, len(value)))
+		if err := mlock(chunk); err != nil && a.lockErr == nil {
+			a.lockErr = err
+		}
+		a.chunks = append(a.chunks, chunk[:0])
+		last++
+	}
+	start := len(a.chunks[last])
+	a.chunks[last] = append(a.chunks[last], value...)
+	return unsafe.String(&a.chunks[last][start], len(value))
+}
+
+// add for bytes that are about to be zeroed, without a string copy between.
+func (a *arena) addBytes(b []byte) string {
+	return a.add(unsafe.String(unsafe.SliceData(b), len(b)))
+}
+
+// Whether value is already a view into a, so that seal need not copy it.
+func (a *arena) holds(value string) bool {
+	p := uintptr(unsafe.Pointer(unsafe.StringData(value)))
+	for _, chunk := range a.chunks {
+		start := uintptr(unsafe.Pointer(unsafe.SliceData(chunk)))
+		if p >= start && p < start+uintptr(cap(chunk)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *arena) wipe() {
+	for _, chunk := range a.chunks {
+		chunk = chunk[:cap(chunk)]
+		clear(chunk)
+		munlock(chunk)
+	}
+	a.chunks = nil
+}
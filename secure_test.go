@@ -0,0 +1,156 @@
+package dotenvx
+
+import (
+	"math/big"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSecureMemory_CloseWipesValues(t *testing.T) {
+	t.Parallel()
+	dir := writeFiles(t, map[string]string{".env": "SECRET=" + testCipher + "\nURL=db://${SECRET}\nPLAIN=plain\n"})
+	l := New(WithDir(dir), WithKeys(testKeyHex), WithSecureMemory(), WithEnviron(environOf()))
+
+	secret, url, plain := l.Getenv("SECRET"), l.Getenv("URL"), l.Getenv("PLAIN")
+	kept := strings.Clone(secret)
+	if secret != "hello" || url != "db://hello" || plain != "plain" {
+		t.Fatalf("Expected the values decrypted, got %q %q %q", secret, url, plain)
+	}
+	if key := l.cache.envFiles[0].Keys[0]; slices.ContainsFunc(key.D.Bits(), func(w big.Word) bool { return w != 0 }) {
+		t.Error("Expected the key zeroed once the file was decrypted")
+	}
+
+	l.Close()
+	if secret != "\x00\x00\x00\x00\x00" || strings.Contains(url, "hello") {
+		t.Errorf("Expected the encrypted values wiped, got %q %q", secret, url)
+	}
+	if kept != "hello" || plain != "plain" {
+		t.Errorf("Expected a clone and a plain value untouched, got %q %q", kept, plain)
+	}
+	if got := l.Getenv("SECRET"); got != "hello" {
+		t.Errorf("Expected a lookup after Close to load afresh, got %q", got)
+	}
+}
+
+func TestSecureMemory_ReloadWipesTheOldSnapshot(t *testing.T) {
+	t.Parallel()
+	dir := writeFiles(t, map[string]string{".env": "SECRET=" + testCipher + "\n"})
+	l := New(WithDir(dir), WithKeys(testKeyHex), WithSecureMemory(), WithEnviron(environOf()))
+
+	before := l.Getenv("SECRET")
+	if err := l.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if before == "hello" || l.Getenv("SECRET") != "hello" {
+		t.Errorf("Expected the old value wiped and the new one served, got %q %q", before, l.Getenv("SECRET"))
+	}
+}
+
+func TestSecureMemory_DecryptsIntoTheArena(t *testing.T) {
+	t.Parallel()
+	dir := writeFiles(t, map[string]string{".env": "SECRET=" + testCipher + "\nURL=db://${SECRET}\n"})
+	l := New(WithDir(dir), WithKeys(testKeyHex), WithSecureMemory(), WithEnviron(environOf()))
+
+	l.Getenv("SECRET")
+	s := l.cache
+	if !s.arena.holds(s.vars[0].Value) || !s.arena.holds(s.vars[1].Value) {
+		t.Error("Expected both values in the arena")
+	}
+	if used := len(s.arena.chunks[0]); used != len("hello")+len("db://hello") {
+		t.Errorf("Expected SECRET decrypted into the arena rather than copied there after, got %d bytes used", used)
+	}
+}
+
+func TestLockKeys_MovesTheKey(t *testing.T) {
+	t.Parallel()
+	keys, err := parsePrivateKeys(testKeyHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bits := keys[0].D.Bits()
+	envFiles := []EnvFile{{".env", keys}, {".env.local", keys}}
+	New().lockKeys(envFiles)
+
+	if slices.ContainsFunc(bits, func(w big.Word) bool { return w != 0 }) || &keys[0].D.Bits()[0] == &bits[0] {
+		t.Error("Expected the key moved and its old memory zeroed")
+	}
+	if plain, err := decryptSecretStrict(keys, testCipher[len(encryptedPrefix):]); err != nil || plain != "hello" {
+		t.Errorf("Expected the moved key to decrypt, got %q %v", plain, err)
+	}
+	zeroKeys(envFiles)
+}
+
+func TestArena_SpansChunks(t *testing.T) {
+	a := &arena{}
+	big := strings.Repeat("x", arenaChunk-1)
+	first, second, huge := a.add(big), a.add("yy"), a.add(big+big)
+	if first != big || second != "yy" || huge != big+big || len(a.chunks) != 3 {
+		t.Errorf("Expected three chunks holding their values, got %d", len(a.chunks))
+	}
+	a.wipe()
+	if strings.Trim(first+second+huge, "\x00") != "" {
+		t.Error("Expected every chunk zeroed")
+	}
+}
+
+func TestWithUnsetKeys(t *testing.T) {
+	dir := writeFiles(t, map[string]string{".env": "SECRET=" + testCipher + "\n"})
+	t.Setenv("DOTENV_PRIVATE_KEY", testKeyHex)
+	t.Setenv("DOTENV_PRIVATE_KEY_UNUSED", testKeyHex)
+
+	l := New(WithDir(dir), WithUnsetKeys())
+	if got := l.Getenv("SECRET"); got != "hello" {
+		t.Fatalf("Expected hello, got %q", got)
+	}
+	for _, name := range []string{"DOTENV_PRIVATE_KEY", "DOTENV_PRIVATE_KEY_UNUSED"} {
+		if _, ok := os.LookupEnv(name); ok {
+			t.Errorf("Expected %s unset once consumed", name)
+		}
+	}
+}
+
+func TestSecureMemory_UnmarshalCopiesAllButSecrets(t *testing.T) {
+	t.Parallel()
+	dir := writeFiles(t, map[string]string{".env": "NAME=" + testCipher + "\nTOKEN=" + testCipher + "\n"})
+	l := New(WithDir(dir), WithKeys(testKeyHex), WithSecureMemory(), WithEnviron(environOf()))
+
+	var cfg struct {
+		Name  string `env:"NAME"`
+		Token Secret `env:"TOKEN"`
+	}
+	if err := l.Unmarshal(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+	if cfg.Name != "hello" || cfg.Token.Reveal() != "\x00\x00\x00\x00\x00" {
+		t.Errorf("Expected Name copied and Token wiped with the snapshot, got %q %q", cfg.Name, cfg.Token.Reveal())
+	}
+}
+
+// Environ builds its lines while holding the snapshot, so a Reload wiping the
+// old one in between must wait for it rather than leave NUL bytes in them.
+func TestSecureMemory_ReloadWaitsForReaders(t *testing.T) {
+	t.Parallel()
+	dir := writeFiles(t, map[string]string{".env": "SECRET=" + testCipher + "\n"})
+	l := New(WithDir(dir), WithKeys(testKeyHex), WithSecureMemory(), WithEnviron(environOf()))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 50 {
+			l.Reload()
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		if env := l.Environ(); len(env) != 1 || env[0] != "SECRET=hello" {
+			t.Fatalf("Expected SECRET=hello throughout, got %q", env)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package dotenvx
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// One field Unmarshal could not fill. Err is ErrNotFound for a required
+// variable that is unset, a *DecryptError for one that did not decrypt, or
+// why the value does not parse -- which never quotes the value, as it may be
+// a secret.
+type FieldError struct {
+	Field string
+	Name  string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s (%s): %v", e.Field, e.Name, e.Err)
+}
+
+func (e *FieldError) Unwrap() error { return e.Err }
+
+// Fills the struct v points to from the variables Getenv serves, with the
+// process environment taking precedence, as dotenvx run would have it:
+//
+//	type Config struct {
+//		Port    int           `env:"PORT,default=8080"`
+//		Timeout time.Duration `env:"TIMEOUT,default=5s"`
+//		Hosts   []string      `env:"HOSTS,sep=;,default=a;b"`
+//		DB      struct {
+//			URL url.URL `env:"URL,required"`
+//		} `env:",prefix=DB_"`
+//	}
+//
+// Strings, Secrets, bools, ints, uints, floats, time.Duration, url.URL,
+// anything that implements encoding.TextUnmarshaler, and pointers, slices and
+// key:value maps of those are supported. After the name the env tag takes
+// required, sep= to split slices and maps on something other than ",", and
+// default=, which comes last and keeps any commas in it. A struct field with no
+// name is filled from variables named with its prefix=. An empty variable
+// counts as unset, in the process environment as in the file. Every field that
+// fails is reported, as *FieldErrors joined into one error. Without any key the
+// process environment alone is used; any other failure to load the file is
+// returned as is. With SecureMemory the struct is given copies, which Reload
+// and Close leave alone, except in Secret fields: those keep views of the
+// snapshot's locked memory and read as NUL bytes once it is wiped.
+func Unmarshal(v any) error {
+	return defaultLoader.Unmarshal(v)
+}
+
+func (l *Loader) Unmarshal(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("Unmarshal needs a pointer to a struct, got %T", v)
+	}
+
+	var loadErr error
+	var errs []error
+	l.readSnapshot(func(s *snapshot) {
+		if s.err != nil && !errors.Is(s.err, ErrNoKey) {
+			loadErr = s.err
+			return
+		}
+		processEnv := l.lookupEnv()
+		lookup := func(name string) (string, error) {
+			if value, ok := processEnv(name); ok && value != "" {
+				return value, nil
+			}
+			i, ok := s.last[name]
+			if !ok {
+				return "", nil
+			}
+			if s.failed != nil && s.failed[i] != nil {
+				return "", s.failed[i]
+			}
+			return s.vars[i].Value, nil
+		}
+		unmarshalStruct(rv.Elem(), "", rv.Elem().Type().Name(), lookup, &errs)
+	})
+	if loadErr != nil {
+		return loadErr
+	}
+	return errors.Join(errs...)
+}
+
+func unmarshalStruct(sv reflect.Value, prefix, path string, lookup func(string) (string, error), errs *[]error) {
+	for i := 0; i < sv.NumField(); i++ {
+		field, fv := sv.Type().Field(i), sv.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fieldPath := path + "." + field.Name
+
+		tag, err := parseTag(field.Tag.Get("env"))
+		name := prefix + tag.name
+		if err != nil {
+			*errs = append(*errs, &FieldError{fieldPath, name, err})
+			continue
+		}
+		if tag.name == "" {
+			if nested := structOf(fv); nested.IsValid() {
+				unmarshalStruct(nested, prefix+tag.prefix, fieldPath, lookup, errs)
+			}
+			continue
+		}
+
+		value, err := lookup(name)
+		if err != nil {
+			*errs = append(*errs, &FieldError{fieldPath, name, err})
+			continue
+		}
+		if value == "" {
+			if tag.hasDefault {
+				value = tag.def
+			} else if tag.required {
+				*errs = append(*errs, &FieldError{fieldPath, name, ErrNotFound})
+				continue
+			} else {
+				continue
+			}
+		}
+		if !holdsSecrets(fv.Type()) {
+			value = strings.Clone(value)
+		}
+		if err := setField(fv, value, tag.sep); err != nil {
+			*errs = append(*errs, &FieldError{fieldPath, name, err})
+		}
+	}
+}
+
+// Secrets, alone or in pointers and slices, keep the snapshot's own string so
+// that wiping it wipes them too; anything else gets a copy that outlives it.
+func holdsSecrets(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	return t == secretType
+}
+
+type envTag struct {
+	name, def, sep, prefix string
+	hasDefault, required   bool
+}
+
+// env:"NAME,required,sep=;,default=a;b". default= takes the rest of the tag,
+// commas and all, which is why it comes last.
+func parseTag(tag string) (envTag, error) {
+	t := envTag{sep: ","}
+	t.name, tag, _ = strings.Cut(tag, ",")
+	for tag != "" {
+		if def, ok := strings.CutPrefix(tag, "default="); ok {
+			t.def, t.hasDefault = def, true
+			break
+		}
+		var option string
+		option, tag, _ = strings.Cut(tag, ",")
+		if option == "required" {
+			t.required = true
+		} else if sep, ok := strings.CutPrefix(option, "sep="); ok && sep != "" {
+			t.sep = sep
+		} else if prefix, ok := strings.CutPrefix(option, "prefix="); ok {
+			t.prefix = prefix
+		} else {
+			return t, fmt.Errorf("env tag option %q is not required, sep=, prefix= or default=", option)
+		}
+	}
+	return t, nil
+}
+
+var (
+	durationType        = reflect.TypeFor[time.Duration]()
+	urlType             = reflect.TypeFor[url.URL]()
+	secretType          = reflect.TypeFor[Secret]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// The struct an untagged field holds, allocated if it is a nil pointer, or the
+// zero Value for anything that is a value in its own right, like url.URL.
+func structOf(fv reflect.Value) reflect.Value {
+	t := fv.Type()
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == urlType || t == secretType || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return reflect.Value{}
+	}
+	if fv.Kind() == reflect.Pointer {
+		if fv.IsNil() {
+			fv.Set(reflect.New(t))
+		}
+		return fv.Elem()
+	}
+	return fv
+}
+
+func setField(v reflect.Value, s, sep string) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setField(v.Elem(), s, sep)
+	}
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+
+	switch v.Type() {
+	case secretType:
+		v.Set(reflect.ValueOf(Secret{s}))
+		return nil
+	case durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("not a valid %s", v.Type())
+		}
+		v.SetInt(int64(d))
+		return nil
+	case urlType:
+		u, err := url.Parse(s)
+		if err != nil {
+			return invalid(v.Type(), err)
+		}
+		v.Set(reflect.ValueOf(*u))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return invalid(v.Type(), err)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 0, v.Type().Bits())
+		if err != nil {
+			return invalid(v.Type(), err)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 0, v.Type().Bits())
+		if err != nil {
+			return invalid(v.Type(), err)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return invalid(v.Type(), err)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		var parts []string
+		if s != "" {
+			parts = strings.Split(s, sep)
+		}
+		slice := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := setField(slice.Index(i), strings.TrimSpace(part), sep); err != nil {
+				return fmt.Errorf("item %d: %w", i, err)
+			}
+		}
+		v.Set(slice)
+	case reflect.Map:
+		m := reflect.MakeMap(v.Type())
+		for _, pair := range strings.Split(s, sep) {
+			key, value, ok := strings.Cut(pair, ":")
+			if !ok {
+				return fmt.Errorf("not a list of key:value pairs")
+			}
+			k, e := reflect.New(v.Type().Key()).Elem(), reflect.New(v.Type().Elem()).Elem()
+			if err := setField(k, strings.TrimSpace(key), sep); err != nil {
+				return fmt.Errorf("key: %w", err)
+			}
+			if err := setField(e, strings.TrimSpace(value), sep); err != nil {
+				return fmt.Errorf("%v: %w", k, err)
+			}
+			m.SetMapIndex(k, e)
+		}
+		v.Set(m)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// strconv and url quote the input in their errors; keep only why it failed
+func invalid(t reflect.Type, err error) error {
+	var numErr *strconv.NumError
+	var urlErr *url.Error
+	switch {
+	case errors.As(err, &numErr):
+		err = numErr.Err
+	case errors.As(err, &urlErr):
+		err = urlErr.Err
+	}
+	return fmt.Errorf("not a valid %s: %w", t, err)
+}
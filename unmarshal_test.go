@@ -0,0 +1,148 @@
+package dotenvx
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUnmarshal(t *testing.T) {
+	t.Parallel()
+	dir := writeFiles(t, map[string]string{".env": strings.Join([]string{
+		"SECRET=" + testCipher,
+		"PORT=9000",
+		"RATIO=0.5",
+		"DEBUG=true",
+		"HOSTS=a; b;c",
+		"WEIGHTS=a:1,b:2",
+		"IP=10.0.0.1",
+		"DB_URL=postgres://db/app",
+		"EMPTY=",
+	}, "\n")})
+	l := New(WithDir(dir), WithKeys(testKeyHex), WithEnviron(environOf("PORT=7000")))
+
+	var cfg struct {
+		Secret  string            `env:"SECRET,required"`
+		Port    int               `env:"PORT,default=8080"`
+		Ratio   float64           `env:"RATIO"`
+		Debug   bool              `env:"DEBUG"`
+		Timeout time.Duration     `env:"TIMEOUT,default=5s"`
+		Empty   string            `env:"EMPTY,default=fallback"`
+		Hosts   []string          `env:"HOSTS,sep=;"`
+		Weights map[string]int    `env:"WEIGHTS"`
+		IP      net.IP            `env:"IP"`
+		Unset   *int              `env:"UNSET"`
+		Labels  map[string]string `env:"LABELS"`
+		DB      *struct {
+			URL  url.URL `env:"URL,required"`
+			Pool uint8   `env:"POOL,default=4"`
+		} `env:",prefix=DB_"`
+		unexported string `env:"SECRET"`
+	}
+	if err := l.Unmarshal(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Secret != "hello" || cfg.Port != 7000 || cfg.Ratio != 0.5 || !cfg.Debug || cfg.Timeout != 5*time.Second || cfg.Empty != "fallback" {
+		t.Errorf("Expected the decrypted secret, the process PORT and the defaults, got %+v", cfg)
+	}
+	if !slices.Equal(cfg.Hosts, []string{"a", "b", "c"}) || len(cfg.Weights) != 2 || cfg.Weights["a"] != 1 || cfg.Weights["b"] != 2 {
+		t.Errorf("Expected the slice and map split, got %v %v", cfg.Hosts, cfg.Weights)
+	}
+	if cfg.IP.String() != "10.0.0.1" || cfg.Unset != nil || cfg.Labels != nil || cfg.unexported != "" {
+		t.Errorf("Expected a TextUnmarshaler filled and unset fields left alone, got %+v", cfg)
+	}
+	if cfg.DB == nil || cfg.DB.URL.Host != "db" || cfg.DB.URL.Path != "/app" || cfg.DB.Pool != 4 {
+		t.Errorf("Expected the prefixed struct filled, got %+v", cfg.DB)
+	}
+}
+
+func TestUnmarshal_ReportsEveryField(t *testing.T) {
+	t.Parallel()
+	dir := writeFiles(t, map[string]string{".env": "PORT=" + testCipher + "\nRATIO=half\nWEIGHTS=a=1\n"})
+	l := New(WithDir(dir), WithKeys(testKeyHex), WithEnviron(environOf()))
+
+	var cfg struct {
+		Port    int            `env:"PORT"`
+		Ratio   float64        `env:"RATIO"`
+		Weights map[string]int `env:"WEIGHTS"`
+		Name    string         `env:"NAME,required"`
+	}
+	err := l.Unmarshal(&cfg)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for the required NAME, got %v", err)
+	}
+	for _, want := range []string{".Port (PORT): not a valid int", ".Ratio (RATIO): not a valid float64", ".Weights (WEIGHTS)", ".Name (NAME)"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in %v", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "hello") || strings.Contains(err.Error(), "half") {
+		t.Errorf("Expected no value in the error, got %v", err)
+	}
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) || fieldErr.Name != "PORT" {
+		t.Errorf("Expected a FieldError for PORT first, got %v", fieldErr)
+	}
+}
+
+func TestUnmarshal_DecryptFailure(t *testing.T) {
+	t.Parallel()
+	dir := writeFiles(t, map[string]string{".env": "SECRET=" + prodCipher + "\nPLAIN=plain\n"})
+
+	var cfg struct {
+		Secret string `env:"SECRET"`
+		Plain  string `env:"PLAIN"`
+	}
+	var decryptErr *DecryptError
+	strict := New(WithDir(dir), WithKeys(testKeyHex), WithEnviron(environOf()))
+	if err := strict.Unmarshal(&cfg); !errors.As(err, &decryptErr) || cfg.Plain != "" {
+		t.Errorf("Expected the whole file unavailable, got %v %+v", err, cfg)
+	}
+
+	lenient := New(WithDir(dir), WithKeys(testKeyHex), WithLenient(), WithEnviron(environOf()))
+	if err := lenient.Unmarshal(&cfg); !errors.As(err, &decryptErr) || decryptErr.Name != "SECRET" || cfg.Plain != "plain" {
+		t.Errorf("Expected a DecryptError for SECRET alone, got %v %+v", err, cfg)
+	}
+}
+
+func TestUnmarshal_NoKeyUsesProcessEnv(t *testing.T) {
+	t.Parallel()
+	l := New(WithDir(t.TempDir()), WithEnviron(environOf("PORT=1")))
+	var cfg struct {
+		Port int `env:"PORT"`
+	}
+	if err := l.Unmarshal(&cfg); err != nil || cfg.Port != 1 {
+		t.Errorf("Expected PORT from the process, got %d %v", cfg.Port, err)
+	}
+	if err := l.Unmarshal(cfg); err == nil {
+		t.Error("Expected an error for a struct that is not a pointer")
+	}
+}
+
+func TestUnmarshal_TagOptions(t *testing.T) {
+	t.Parallel()
+	dir := writeFiles(t, map[string]string{".env": "NAME=from file\n"})
+	l := New(WithDir(dir), WithKeys(testKeyHex), WithEnviron(environOf("NAME=")))
+
+	var cfg struct {
+		Name  string   `env:"NAME,required"`
+		Hosts []string `env:"HOSTS,sep=;,default=a,b;c"`
+	}
+	if err := l.Unmarshal(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Name != "from file" || !slices.Equal(cfg.Hosts, []string{"a,b", "c"}) {
+		t.Errorf("Expected the file's NAME past the empty one and the default's comma kept, got %+v", cfg)
+	}
+
+	var bad struct {
+		Port int `env:"PORT,requird"`
+	}
+	if err := l.Unmarshal(&bad); err == nil || !strings.Contains(err.Error(), `"requird"`) {
+		t.Errorf("Expected the unknown option reported, got %v", err)
+	}
+}